@@ -0,0 +1,341 @@
+package anki
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLiteドライバー（CGO不要）
+)
+
+// パッケージ内のファイル名
+const (
+	collectionFile       = "collection.anki2"
+	collectionFileAnki21 = "collection.anki21"
+	collectionFileLatest = "collection.anki21b"
+	mediaFile            = "media"
+)
+
+// カードの種類（cards.type）
+const (
+	CardTypeNew        = 0
+	CardTypeLearning   = 1
+	CardTypeReview     = 2
+	CardTypeRelearning = 3
+)
+
+// fieldSeparator はnotes.fldsのフィールド区切り文字です
+const fieldSeparator = "\x1f"
+
+// MaxCollectionSize は展開するコレクションの最大サイズです
+const MaxCollectionSize = 200 << 20
+
+var (
+	// ErrUnsupportedFormat は新しい形式（zstd圧縮のanki21b）など、読み込めない形式の場合のエラーです
+	ErrUnsupportedFormat = errors.New("unsupported apkg format")
+	// ErrNoCollection はパッケージにコレクションが含まれない場合のエラーです
+	ErrNoCollection = errors.New("apkg does not contain a collection")
+)
+
+// Package はapkgファイルの内容です
+type Package struct {
+	DeckName   string
+	Notes      []Note
+	MediaCount int
+}
+
+// Note はAnkiのノート（1単語）です
+type Note struct {
+	GUID   string
+	Fields []string
+	Tags   []string
+	Card   *Card
+}
+
+// Card はノートの最初のカードの学習状態です
+type Card struct {
+	Type       int
+	Interval   int     // 日数（復習カードのみ）
+	EaseFactor float64 // 2.5などの倍率
+	Reps       int
+	Lapses     int
+	DueAt      *time.Time
+	LastReview *time.Time
+}
+
+// Front はノートの表面（1番目のフィールド）をHTMLを除去して返します
+func (n *Note) Front() string {
+	return n.field(0)
+}
+
+// Back はノートの裏面（2番目のフィールド）をHTMLを除去して返します
+func (n *Note) Back() string {
+	return n.field(1)
+}
+
+func (n *Note) field(i int) string {
+	if i >= len(n.Fields) {
+		return ""
+	}
+	return StripHTML(n.Fields[i])
+}
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	soundPattern     = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	spacePattern     = regexp.MustCompile(`\s+`)
+)
+
+// StripHTML はフィールドからHTMLタグと音声タグを除去してプレーンテキストにします
+func StripHTML(value string) string {
+	value = htmlBreakPattern.ReplaceAllString(value, " ")
+	value = htmlTagPattern.ReplaceAllString(value, "")
+	value = soundPattern.ReplaceAllString(value, "")
+	value = html.UnescapeString(value)
+	return strings.TrimSpace(spacePattern.ReplaceAllString(value, " "))
+}
+
+// Read はapkgファイルを読み込みます
+func Read(r io.ReaderAt, size int64) (*Package, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apkg archive: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	// anki21はanki2と同じスキーマ。新しいクライアントは互換用のanki2も同梱する
+	collection := files[collectionFileAnki21]
+	if collection == nil {
+		collection = files[collectionFile]
+	}
+	if collection == nil {
+		if files[collectionFileLatest] != nil {
+			return nil, ErrUnsupportedFormat
+		}
+		return nil, ErrNoCollection
+	}
+
+	path, err := extractToTemp(collection)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	pkg, err := readCollection(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if media := files[mediaFile]; media != nil {
+		pkg.MediaCount = countMedia(media)
+	}
+	return pkg, nil
+}
+
+// extractToTemp はzip内のファイルを一時ファイルに展開します（SQLiteはファイルパスが必要なため）
+func extractToTemp(f *zip.File) (string, error) {
+	if f.UncompressedSize64 > MaxCollectionSize {
+		return "", fmt.Errorf("collection is too large")
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "anki-*.sqlite")
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	// ヘッダーのサイズは偽装できるため、展開したサイズでも確認する
+	n, err := io.Copy(dst, io.LimitReader(src, MaxCollectionSize+1))
+	if err == nil && n > MaxCollectionSize {
+		err = fmt.Errorf("collection is too large")
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
+
+// countMedia はmediaファイル（ファイル番号と名前のJSON）からメディア数を数えます
+func countMedia(f *zip.File) int {
+	src, err := f.Open()
+	if err != nil {
+		return 0
+	}
+	defer src.Close()
+
+	var media map[string]string
+	if err := json.NewDecoder(io.LimitReader(src, 10<<20)).Decode(&media); err != nil {
+		return 0
+	}
+	return len(media)
+}
+
+// readCollection はSQLiteのコレクションからノートと学習状態を読み込みます
+func readCollection(path string) (*Package, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var crt int64
+	var decksJSON string
+	if err := db.QueryRow("SELECT crt, decks FROM col LIMIT 1").Scan(&crt, &decksJSON); err != nil {
+		return nil, fmt.Errorf("invalid collection: %w", err)
+	}
+	created := time.Unix(crt, 0)
+
+	deckNames := make(map[int64]string)
+	var decks map[string]struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err == nil {
+		for _, deck := range decks {
+			deckNames[deck.ID] = deck.Name
+		}
+	}
+
+	lastReviews, err := readLastReviews(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// ノートごとに最初のカード（ord最小）の状態を取得
+	rows, err := db.Query(`
+		SELECT n.guid, n.flds, n.tags,
+			c.id, c.did, c.type, c.due, c.ivl, c.factor, c.reps, c.lapses
+		FROM notes n
+		LEFT JOIN cards c ON c.id = (
+			SELECT id FROM cards WHERE nid = n.id ORDER BY ord LIMIT 1
+		)
+		ORDER BY n.id`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collection: %w", err)
+	}
+	defer rows.Close()
+
+	pkg := &Package{}
+	deckCounts := make(map[int64]int)
+	for rows.Next() {
+		var (
+			guid, flds, tags                    string
+			cardID, deckID, due                 sql.NullInt64
+			cardType, ivl, factor, reps, lapses sql.NullInt64
+		)
+		if err := rows.Scan(&guid, &flds, &tags, &cardID, &deckID, &cardType, &due, &ivl, &factor, &reps, &lapses); err != nil {
+			return nil, err
+		}
+
+		note := Note{
+			GUID:   guid,
+			Fields: strings.Split(flds, fieldSeparator),
+			Tags:   strings.Fields(tags),
+		}
+		if cardID.Valid {
+			deckCounts[deckID.Int64]++
+			note.Card = convertCard(created, cardType.Int64, due.Int64, ivl.Int64, factor.Int64, reps.Int64, lapses.Int64)
+			if last, ok := lastReviews[cardID.Int64]; ok {
+				note.Card.LastReview = &last
+			}
+		}
+		pkg.Notes = append(pkg.Notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 最も多くのカードを含むデッキの名前をパッケージ名にする
+	best := -1
+	for deckID, count := range deckCounts {
+		if count > best {
+			best = count
+			pkg.DeckName = deckNames[deckID]
+		}
+	}
+	return pkg, nil
+}
+
+// readLastReviews はカードごとの最終復習日時を復習履歴(revlog)から取得します
+func readLastReviews(db *sql.DB) (map[int64]time.Time, error) {
+	rows, err := db.Query("SELECT cid, MAX(id) FROM revlog GROUP BY cid")
+	if err != nil {
+		return nil, fmt.Errorf("invalid collection: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := make(map[int64]time.Time)
+	for rows.Next() {
+		var cardID, reviewedAt int64
+		if err := rows.Scan(&cardID, &reviewedAt); err != nil {
+			return nil, err
+		}
+		// revlog.idはミリ秒単位のUNIX時刻
+		reviews[cardID] = time.UnixMilli(reviewedAt)
+	}
+	return reviews, rows.Err()
+}
+
+// convertCard はcardsテーブルの値を学習状態に変換します
+func convertCard(created time.Time, cardType, due, ivl, factor, reps, lapses int64) *Card {
+	card := &Card{
+		Type:   int(cardType),
+		Reps:   int(reps),
+		Lapses: int(lapses),
+	}
+	if factor > 0 {
+		card.EaseFactor = float64(factor) / 1000
+	}
+	// ivlが負の場合は秒単位（学習中）
+	if ivl > 0 {
+		card.Interval = int(ivl)
+	}
+
+	// 一時停止・埋没でqueueは負の値になるため、typeで判定する
+	switch cardType {
+	case CardTypeReview:
+		// dueはコレクション作成日からの日数
+		dueAt := created.AddDate(0, 0, int(due))
+		card.DueAt = &dueAt
+	case CardTypeLearning, CardTypeRelearning:
+		// dueはUNIX時刻（秒）。日単位の学習中カードは日数の場合がある
+		if due > 1_000_000_000 {
+			dueAt := time.Unix(due, 0)
+			card.DueAt = &dueAt
+		} else {
+			dueAt := created.AddDate(0, 0, int(due))
+			card.DueAt = &dueAt
+		}
+	}
+	return card
+}
+
+// checksum はソートフィールドのチェックサム（SHA1の先頭8桁）を計算します
+func checksum(value string) int64 {
+	sum := sha1.Sum([]byte(StripHTML(value)))
+	n, _ := strconv.ParseInt(hex.EncodeToString(sum[:])[:8], 16, 64)
+	return n
+}
@@ -0,0 +1,185 @@
+package anki
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fixtureCreated はtestdata/fruits.apkgのコレクション作成日（col.crt）です
+var fixtureCreated = time.Unix(1704067200, 0)
+
+func readFile(t *testing.T, path string) (*Package, error) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Read(bytes.NewReader(data), int64(len(data)))
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestReadFixture(t *testing.T) {
+	pkg, err := readFile(t, "testdata/fruits.apkg")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if pkg.DeckName != "English::Fruits" {
+		t.Errorf("DeckName = %q, want %q", pkg.DeckName, "English::Fruits")
+	}
+	if pkg.MediaCount != 1 {
+		t.Errorf("MediaCount = %d, want 1", pkg.MediaCount)
+	}
+
+	tests := []struct {
+		guid  string
+		front string
+		back  string
+		tags  []string
+		card  Card
+	}{
+		{"A1b2C3d4E5", "apple", "りんご & 果物", []string{"fruit", "food"}, Card{
+			Type: CardTypeReview, Interval: 21, EaseFactor: 2.3, Reps: 5, Lapses: 1,
+			DueAt:      timePtr(fixtureCreated.AddDate(0, 0, 30)),
+			LastReview: timePtr(time.UnixMilli(1704153600000)),
+		}},
+		{"F6g7H8i9J0", "banana", "バナナ", nil, Card{Type: CardTypeNew}},
+		{"K1l2M3n4O5", "cherry", "さくらんぼ", []string{"fruit"}, Card{
+			Type: CardTypeLearning, EaseFactor: 2.5, Reps: 1,
+			DueAt:      timePtr(time.Unix(1704200000, 0)),
+			LastReview: timePtr(time.UnixMilli(1704100000000)),
+		}},
+		// 複数のカードを持つノートはordが最小のカードの状態を使う
+		{"P6q7R8s9T0", "grape", "ぶどう", nil, Card{Type: CardTypeNew}},
+	}
+
+	// 互換用のanki2ではなくanki21を読み込む
+	if len(pkg.Notes) != len(tests) {
+		t.Fatalf("len(Notes) = %d, want %d", len(pkg.Notes), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.guid, func(t *testing.T) {
+			note := pkg.Notes[i]
+			if note.GUID != tt.guid {
+				t.Errorf("GUID = %q, want %q", note.GUID, tt.guid)
+			}
+			if note.Front() != tt.front || note.Back() != tt.back {
+				t.Errorf("Front, Back = %q, %q, want %q, %q", note.Front(), note.Back(), tt.front, tt.back)
+			}
+			if !equalTags(note.Tags, tt.tags) {
+				t.Errorf("Tags = %v, want %v", note.Tags, tt.tags)
+			}
+			if note.Card == nil {
+				t.Fatal("Card = nil")
+			}
+			assertCard(t, *note.Card, tt.card)
+		})
+	}
+}
+
+func TestReadUnsupportedFormat(t *testing.T) {
+	if _, err := readFile(t, "testdata/latest.apkg"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Read() error = %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	want := &Package{
+		DeckName: "Round Trip",
+		Notes: []Note{
+			{GUID: "guid-review", Fields: []string{"apple", "りんご"}, Tags: []string{"fruit"}, Card: &Card{
+				Type: CardTypeReview, Interval: 6, EaseFactor: 2.36, Reps: 3, Lapses: 1,
+				DueAt:      timePtr(day.AddDate(0, 0, 4)),
+				LastReview: timePtr(now.Add(-48 * time.Hour)),
+			}},
+			// 期限切れのカードでも期日が変わらない
+			{GUID: "guid-overdue", Fields: []string{"banana", "バナナ"}, Card: &Card{
+				Type: CardTypeReview, Interval: 10, EaseFactor: 2.5, Reps: 4,
+				DueAt:      timePtr(day.AddDate(0, 0, -3)),
+				LastReview: timePtr(now.AddDate(0, 0, -13)),
+			}},
+			{GUID: "guid-learning", Fields: []string{"cherry", "さくらんぼ"}, Card: &Card{
+				Type: CardTypeLearning, EaseFactor: 2.5, Reps: 1,
+				DueAt:      timePtr(now.Add(10 * time.Minute)),
+				LastReview: timePtr(now),
+			}},
+			{GUID: "guid-new", Fields: []string{"grape", "ぶどう"}, Card: &Card{Type: CardTypeNew}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, want, now); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if got.DeckName != want.DeckName {
+		t.Errorf("DeckName = %q, want %q", got.DeckName, want.DeckName)
+	}
+	if len(got.Notes) != len(want.Notes) {
+		t.Fatalf("len(Notes) = %d, want %d", len(got.Notes), len(want.Notes))
+	}
+	for i, w := range want.Notes {
+		t.Run(w.GUID, func(t *testing.T) {
+			note := got.Notes[i]
+			if note.GUID != w.GUID {
+				t.Errorf("GUID = %q, want %q", note.GUID, w.GUID)
+			}
+			if !reflect.DeepEqual(note.Fields, w.Fields) {
+				t.Errorf("Fields = %v, want %v", note.Fields, w.Fields)
+			}
+			if !equalTags(note.Tags, w.Tags) {
+				t.Errorf("Tags = %v, want %v", note.Tags, w.Tags)
+			}
+			if note.Card == nil {
+				t.Fatal("Card = nil")
+			}
+			assertCard(t, *note.Card, *w.Card)
+		})
+	}
+}
+
+func assertCard(t *testing.T, got, want Card) {
+	t.Helper()
+	if got.Type != want.Type || got.Interval != want.Interval || got.Reps != want.Reps || got.Lapses != want.Lapses {
+		t.Errorf("type, interval, reps, lapses = %d, %d, %d, %d, want %d, %d, %d, %d",
+			got.Type, got.Interval, got.Reps, got.Lapses, want.Type, want.Interval, want.Reps, want.Lapses)
+	}
+	if got.EaseFactor != want.EaseFactor {
+		t.Errorf("EaseFactor = %v, want %v", got.EaseFactor, want.EaseFactor)
+	}
+	if !equalTime(got.DueAt, want.DueAt) {
+		t.Errorf("DueAt = %v, want %v", got.DueAt, want.DueAt)
+	}
+	if !equalTime(got.LastReview, want.LastReview) {
+		t.Errorf("LastReview = %v, want %v", got.LastReview, want.LastReview)
+	}
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// equalTags はタグを比較します（タグなしはnilと空のスライスを区別しない）
+func equalTags(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,346 @@
+package anki
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// collectionSchema はAnki 2.1互換（スキーマバージョン11）のコレクションのテーブル定義です
+const collectionSchema = `
+CREATE TABLE col (
+	id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+	ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+	conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+);
+CREATE TABLE notes (
+	id integer primary key, guid text not null, mid integer not null, mod integer not null,
+	usn integer not null, tags text not null, flds text not null, sfld integer not null,
+	csum integer not null, flags integer not null, data text not null
+);
+CREATE TABLE cards (
+	id integer primary key, nid integer not null, did integer not null, ord integer not null,
+	mod integer not null, usn integer not null, type integer not null, queue integer not null,
+	due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+	lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+	flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+	id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+	ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+	type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// カードのキュー（cards.queue）
+const (
+	queueNew      = 0
+	queueLearning = 1
+	queueReview   = 2
+)
+
+// defaultDeckID はAnkiの「Default」デッキのIDです
+const defaultDeckID = 1
+
+// defaultEaseFactor はAnkiの初期ease（250%）です
+const defaultEaseFactor = 2500
+
+// Write はパッケージをapkg形式（collection.anki2とmedia）で書き出します
+// ノートは表面・裏面の2フィールドを持つBasicノートタイプとして、1つのデッキに出力されます
+func Write(w io.Writer, pkg *Package, now time.Time) error {
+	file, err := os.CreateTemp("", "anki-*.sqlite")
+	if err != nil {
+		return err
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	if err := writeCollection(path, pkg, now); err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	if err := addFile(archive, collectionFile, path); err != nil {
+		return err
+	}
+	media, err := archive.Create(mediaFile)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(media, "{}"); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// addFile はファイルをzipに追加します
+func addFile(archive *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// writeCollection はSQLiteのコレクションを作成してノートとカードを書き込みます
+func writeCollection(path string, pkg *Package, now time.Time) error {
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(collectionSchema); err != nil {
+		return err
+	}
+
+	// IDはAnkiの慣例に合わせてミリ秒単位のUNIX時刻を基準にする
+	base := now.UnixMilli()
+	modelID := base
+	deckID := base + 1
+	created := collectionCreated(pkg, now)
+	deckName := pkg.DeckName
+	if deckName == "" {
+		deckName = "Imported"
+	}
+
+	if err := insertCol(tx, created, now, modelID, deckID, deckName, len(pkg.Notes)); err != nil {
+		return err
+	}
+
+	usedReviewIDs := make(map[int64]bool)
+	for i := range pkg.Notes {
+		note := &pkg.Notes[i]
+		noteID := base + int64(i)
+		cardID := base + int64(i)
+
+		guid := note.GUID
+		if guid == "" {
+			if guid, err = newGUID(); err != nil {
+				return err
+			}
+		}
+		front := ""
+		if len(note.Fields) > 0 {
+			front = note.Fields[0]
+		}
+		tags := ""
+		if len(note.Tags) > 0 {
+			tags = " " + strings.Join(note.Tags, " ") + " "
+		}
+
+		_, err := tx.Exec(`INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data)
+			VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, guid, modelID, now.Unix(), tags, strings.Join(note.Fields, fieldSeparator), StripHTML(front), checksum(front))
+		if err != nil {
+			return err
+		}
+
+		cardType, queue, due, ivl, factor, reps, lapses := cardColumns(note.Card, created, i+1)
+		_, err = tx.Exec(`INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+			VALUES (?, ?, ?, 0, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')`,
+			cardID, noteID, deckID, now.Unix(), cardType, queue, due, ivl, factor, reps, lapses)
+		if err != nil {
+			return err
+		}
+
+		// 最終復習日時は復習履歴として残す（revlog.idはミリ秒単位で一意）
+		if note.Card != nil && note.Card.LastReview != nil {
+			reviewID := note.Card.LastReview.UnixMilli()
+			for usedReviewIDs[reviewID] {
+				reviewID++
+			}
+			usedReviewIDs[reviewID] = true
+			_, err = tx.Exec(`INSERT INTO revlog (id, cid, usn, ease, ivl, lastIvl, factor, time, type)
+				VALUES (?, ?, -1, 3, ?, 0, ?, 0, 1)`,
+				reviewID, cardID, ivl, factor)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// collectionCreated はコレクションの作成日（日付の基準）を決めます
+// 期限切れのカードの期日が負にならないよう、最も古い期日・復習日時の日付の開始時刻にします
+func collectionCreated(pkg *Package, now time.Time) time.Time {
+	earliest := now
+	for i := range pkg.Notes {
+		card := pkg.Notes[i].Card
+		if card == nil {
+			continue
+		}
+		if card.DueAt != nil && card.DueAt.Before(earliest) {
+			earliest = *card.DueAt
+		}
+		if card.LastReview != nil && card.LastReview.Before(earliest) {
+			earliest = *card.LastReview
+		}
+	}
+	return earliest.UTC().Truncate(24 * time.Hour)
+}
+
+// cardColumns は学習状態をcardsテーブルの値に変換します
+// positionは未学習カードの出題順です
+func cardColumns(card *Card, created time.Time, position int) (cardType, queue int, due int64, ivl, factor, reps, lapses int) {
+	if card == nil || card.Type == CardTypeNew || card.DueAt == nil {
+		return CardTypeNew, queueNew, int64(position), 0, 0, 0, 0
+	}
+
+	factor = defaultEaseFactor
+	if card.EaseFactor > 0 {
+		factor = int(card.EaseFactor * 1000)
+	}
+	reps, lapses = card.Reps, card.Lapses
+
+	switch card.Type {
+	case CardTypeLearning, CardTypeRelearning:
+		// 学習中カードの期日はUNIX時刻（秒）
+		return card.Type, queueLearning, card.DueAt.Unix(), card.Interval, factor, reps, lapses
+	default:
+		// 復習カードの期日はコレクション作成日からの日数
+		ivl = card.Interval
+		if ivl < 1 {
+			ivl = 1
+		}
+		days := int64(card.DueAt.Sub(created) / (24 * time.Hour))
+		return CardTypeReview, queueReview, days, ivl, factor, reps, lapses
+	}
+}
+
+// insertCol はコレクションの設定（ノートタイプ・デッキ・デッキ設定）を書き込みます
+func insertCol(tx *sql.Tx, created, now time.Time, modelID, deckID int64, deckName string, noteCount int) error {
+	conf := map[string]interface{}{
+		"nextPos":       noteCount + 1,
+		"estTimes":      true,
+		"activeDecks":   []int64{deckID},
+		"sortType":      "noteFld",
+		"timeLim":       0,
+		"sortBackwards": false,
+		"addToCur":      true,
+		"curDeck":       deckID,
+		"newSpread":     0,
+		"dueCounts":     true,
+		"curModel":      modelID,
+		"collapseTime":  1200,
+	}
+
+	field := func(name string, ord int) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name, "ord": ord, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+	models := map[string]interface{}{
+		fmt.Sprint(modelID): map[string]interface{}{
+			"id":    modelID,
+			"name":  "Basic",
+			"type":  0,
+			"mod":   now.Unix(),
+			"usn":   -1,
+			"sortf": 0,
+			"did":   deckID,
+			"tmpls": []map[string]interface{}{{
+				"name":  "Card 1",
+				"ord":   0,
+				"qfmt":  "{{Front}}",
+				"afmt":  "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+				"did":   nil,
+				"bqfmt": "",
+				"bafmt": "",
+			}},
+			"flds":      []map[string]interface{}{field("Front", 0), field("Back", 1)},
+			"css":       ".card {\n font-family: arial;\n font-size: 20px;\n text-align: center;\n color: black;\n background-color: white;\n}\n",
+			"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+			"req":       []interface{}{[]interface{}{0, "any", []int{0}}},
+			"tags":      []string{},
+			"vers":      []interface{}{},
+		},
+	}
+
+	deck := func(id int64, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "name": name, "mod": now.Unix(), "usn": -1, "desc": "",
+			"dyn": 0, "conf": 1, "collapsed": false,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+			"extendNew": 10, "extendRev": 50,
+		}
+	}
+	decks := map[string]interface{}{
+		fmt.Sprint(defaultDeckID): deck(defaultDeckID, "Default"),
+		fmt.Sprint(deckID):        deck(deckID, deckName),
+	}
+
+	dconf := map[string]interface{}{
+		"1": map[string]interface{}{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true,
+			"timer": 0, "replayq": true, "dyn": false,
+			"new": map[string]interface{}{
+				"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": defaultEaseFactor,
+				"order": 1, "perDay": 20, "bury": true, "separate": true,
+			},
+			"rev": map[string]interface{}{
+				"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500,
+				"bury": true, "minSpace": 1,
+			},
+			"lapse": map[string]interface{}{
+				"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0,
+			},
+		},
+	}
+
+	values := make([]string, 0, 4)
+	for _, v := range []interface{}{conf, models, decks, dconf} {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		values = append(values, string(encoded))
+	}
+
+	_, err := tx.Exec(`INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags)
+		VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		created.Unix(), now.UnixMilli(), now.UnixMilli(), values[0], values[1], values[2], values[3])
+	return err
+}
+
+// newGUID はノートのGUIDを生成します
+func newGUID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
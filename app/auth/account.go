@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/mail"
+)
+
+// mailTimeout はメール送信のタイムアウトです
+const mailTimeout = 30 * time.Second
+
+var (
+	mailer           mail.Mailer = mail.LogMailer{}
+	appURL                       = "http://localhost:3000"
+	passwordResetTTL             = time.Hour
+)
+
+// InitAuth はアカウント管理の設定とメール送信を初期化します
+func InitAuth(cfg config.AuthConfig, m mail.Mailer, baseURL string) error {
+	if m != nil {
+		mailer = m
+	}
+	if baseURL != "" {
+		appURL = strings.TrimRight(baseURL, "/")
+	}
+	if cfg.PasswordResetTTL > 0 {
+		passwordResetTTL = cfg.PasswordResetTTL
+	}
+	initEmailVerification(cfg.EmailVerificationTTL, cfg.EmailVerificationCooldown, cfg.EmailVerificationPolicy)
+	initLoginLimiter(cfg.LoginLimit)
+	initPasswordHasher(cfg.PasswordHash)
+	initMagicLink(cfg.MagicLinkTTL, cfg.MagicLinkCooldown, cfg.MagicLinkAutoCreate)
+	return initMFA(cfg.MFAIssuer, cfg.MFAChallengeTTL, cfg.MFASecretKey)
+}
+
+// appLink はフロントエンドのパスにトークンをクエリとして付けたURLを返します
+func appLink(path, token string) string {
+	return appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendMailAsync はメールをバックグラウンドで送信します
+// 送信の成否や所要時間でメールアドレスの登録有無が分からないよう、レスポンスを待たせません
+func sendMailAsync(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := mailer.Send(ctx, msg); err != nil {
+			log.Printf("Warning: Failed to send mail to %s: %v", msg.To, err)
+		}
+	}()
+}
@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIキーのパラメータ
+const (
+	apiKeyPrefix            = "tk_"
+	apiKeyDefaultDays       = 90
+	apiKeyMaxDays           = 365
+	maxAPIKeysPerUser       = 20
+	apiKeyTouchInterval     = time.Minute // 最終使用日時を更新する最小間隔
+	apiKeyDisplayPrefixSize = 8           // 一覧に表示するキーの先頭の文字数（tk_を除く）
+)
+
+// apiKeyResources はAPIキーで利用できるリソース（/api/v1/<リソース>）と、writeスコープがあるかです
+// GETはread、それ以外はwriteのスコープが必要です。ここにないルート（プロフィール、セッション、
+// APIキー自体、管理用ルート、/auth）はAPIキーでは利用できません
+var apiKeyResources = map[string]bool{
+	"words":      true,
+	"categories": false, // カテゴリの変更は管理用ルートのみ
+	"reviews":    true,
+	"quizzes":    true,
+	"decks":      true,
+}
+
+var (
+	errInvalidAPIKey = errors.New("invalid api key")
+	errAPIKeyExpired = errors.New("api key expired")
+)
+
+// isAPIKey はBearerトークンがAPIキーかを返します
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// authenticateAPIKey はAPIキーを検証し、所有者のクレームとスコープを返します
+// 最終使用日時はapiKeyTouchIntervalごとに更新します
+func authenticateAPIKey(token string, now time.Time) (*Claims, []string, error) {
+	var key models.APIKey
+	err := database.GetDB().Where("key_hash = ? AND revoked_at IS NULL", hashToken(token)).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !now.Before(key.ExpiresAt) {
+		return nil, nil, errAPIKeyExpired
+	}
+
+	var user models.User
+	if err := database.GetDB().Select("user_id", "username").Where("user_id = ?", key.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := database.GetDB().Model(&key).Update("last_used_at", now).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// APIキーには役割を持たせない（管理用ルートは利用できない）
+	claims := &Claims{
+		UserID:   user.ID,
+		Username: user.Username,
+	}
+	return claims, key.ScopeList(), nil
+}
+
+// requiredScope はリクエストのルートに必要なスコープを返します
+// APIキーで利用できないルートの場合はfalseを返します
+func requiredScope(c *gin.Context) (string, bool) {
+	path := strings.TrimPrefix(c.FullPath(), "/api/v1/")
+	if path == c.FullPath() {
+		return "", false
+	}
+	resource, _, _ := strings.Cut(path, "/")
+	if _, ok := apiKeyResources[resource]; !ok {
+		return "", false
+	}
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return resource + ":read", true
+	}
+	return resource + ":write", true
+}
+
+// hasScope はスコープの一覧にscopeが含まれるかを返します（writeはreadを含む）
+func hasScope(scopes []string, scope string) bool {
+	resource, _, _ := strings.Cut(scope, ":")
+	for _, s := range scopes {
+		if s == scope || s == resource+":write" {
+			return true
+		}
+	}
+	return false
+}
+
+// validScopes はAPIキーに付与できるスコープの一覧です
+func validScopes() []string {
+	scopes := make([]string, 0, len(apiKeyResources)*2)
+	for resource, writable := range apiKeyResources {
+		scopes = append(scopes, resource+":read")
+		if writable {
+			scopes = append(scopes, resource+":write")
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// normalizeScopes はスコープを検証し、重複を除いて並べ替えます
+func normalizeScopes(scopes []string) ([]string, bool) {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		resource, action, _ := strings.Cut(scope, ":")
+		writable, ok := apiKeyResources[resource]
+		if !ok || (action != "read" && (action != "write" || !writable)) {
+			return nil, false
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	sort.Strings(result)
+	return result, len(result) > 0
+}
+
+// ListAPIKeysHandler はAPIキー一覧取得ハンドラーです（認証が必要）
+// 失効済み・期限切れのキーは含みません
+func ListAPIKeysHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var keys []models.APIKey
+	err := database.GetDB().
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", claims.UserID, time.Now()).
+		Order("created_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	responses := make([]models.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		responses = append(responses, keys[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": responses,
+		"scopes":   validScopes(),
+	})
+}
+
+// CreateAPIKeyHandler はAPIキー作成ハンドラーです（認証が必要）
+// キーはハッシュのみ保存するため、レスポンスでこの時だけ返します
+func CreateAPIKeyHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid name. Must be 1 to 100 characters"})
+		return
+	}
+	scopes, ok := normalizeScopes(req.Scopes)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scopes. Must be one or more of " + strings.Join(validScopes(), ", ")})
+		return
+	}
+	days := apiKeyDefaultDays
+	if req.ExpiresInDays != nil {
+		days = *req.ExpiresInDays
+		if days < 1 || days > apiKeyMaxDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expires_in_days. Must be between 1 and " + strconv.Itoa(apiKeyMaxDays)})
+			return
+		}
+	}
+
+	now := time.Now()
+	var count int64
+	err := database.GetDB().Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", claims.UserID, now).
+		Count(&count).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count API keys"})
+		return
+	}
+	if count >= maxAPIKeysPerUser {
+		c.JSON(http.StatusConflict, gin.H{"error": "Too many API keys. Revoke an existing key first"})
+		return
+	}
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+	plain := apiKeyPrefix + secret
+
+	key := models.APIKey{
+		UserID:    claims.UserID,
+		Name:      name,
+		Prefix:    plain[:len(apiKeyPrefix)+apiKeyDisplayPrefixSize],
+		KeyHash:   hashToken(plain),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: now.AddDate(0, 0, days),
+		CreatedAt: now,
+	}
+	if err := database.GetDB().Create(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreateAPIKeyResponse{
+		Message: "API key created successfully. Store it now; it will not be shown again",
+		Key:     plain,
+		APIKey:  key.ToResponse(),
+	})
+}
+
+// RevokeAPIKeyHandler はAPIキーの失効ハンドラーです（認証が必要）
+func RevokeAPIKeyHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	result := database.GetDB().Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, claims.UserID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked successfully",
+	})
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/database"
+	"backend/mail"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 未確認アカウントの制限ポリシー
+const (
+	// VerificationPolicyNone は未確認アカウントを制限しません
+	VerificationPolicyNone = "none"
+	// VerificationPolicyRestrict は一括インポートや共有などの一部の機能のみ制限します
+	VerificationPolicyRestrict = "restrict"
+	// VerificationPolicyStrict はプロフィールとセッション管理以外の全ての機能を制限します
+	VerificationPolicyStrict = "strict"
+)
+
+// verificationPolicyRanks はポリシーの厳しさの順序です
+var verificationPolicyRanks = map[string]int{
+	VerificationPolicyNone:     0,
+	VerificationPolicyRestrict: 1,
+	VerificationPolicyStrict:   2,
+}
+
+var (
+	emailVerificationTTL      = 48 * time.Hour
+	emailVerificationCooldown = time.Minute
+	emailVerificationPolicy   = VerificationPolicyRestrict
+)
+
+var (
+	// errInvalidVerificationToken は存在しない・使用済み・期限切れの確認トークンのエラーです
+	errInvalidVerificationToken = errors.New("invalid or expired verification token")
+	// errEmailTaken はメールアドレスが他のユーザーに使用されている場合のエラーです
+	errEmailTaken = errors.New("email already exists")
+)
+
+// sendVerificationEmail は確認トークンを発行して確認メールを送信します
+// emailには確認するメールアドレスを指定します（メールアドレス変更時は新しいアドレス）
+func sendVerificationEmail(tx *gorm.DB, user *models.User, email string, now time.Time) error {
+	record := models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     email,
+		ExpiresAt: now.Add(emailVerificationTTL),
+		CreatedAt: now,
+	}
+	token, err := issueSingleUseToken(tx, &record, &record.TokenHash, "user_id = ?", user.ID)
+	if err != nil {
+		return err
+	}
+
+	sendMailAsync(mail.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThis link expires in %s. If you did not create an account, you can ignore this email.\n",
+			user.Username, appLink("/verify-email", token), emailVerificationTTL),
+	})
+	return nil
+}
+
+// VerifyEmailHandler はメールアドレス確認ハンドラーです
+func VerifyEmailHandler(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	var user models.User
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var token models.EmailVerificationToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hashToken(req.Token)).First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidVerificationToken
+		}
+		if err != nil {
+			return err
+		}
+		if token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+			return errInvalidVerificationToken
+		}
+
+		if err := tx.Where("user_id = ?", token.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		// メールアドレス変更の申請後に他のユーザーが登録した場合
+		if taken, err := emailTaken(tx, token.Email, user.ID); err != nil {
+			return err
+		} else if taken {
+			return errEmailTaken
+		}
+		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		user.Email = token.Email
+		user.EmailVerifiedAt = &now
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"email":             user.Email,
+			"email_verified_at": now,
+		}).Error
+	})
+	if errors.Is(err, errInvalidVerificationToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+		"user":    user.ToResponse(),
+	})
+}
+
+// ResendVerificationHandler は確認メールの再送ハンドラーです（認証が必要）
+// 前回の送信からemailVerificationCooldownが経過するまでは429を返します
+func ResendVerificationHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().Where("user_id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email address is already verified"})
+		return
+	}
+
+	now := time.Now()
+	var last models.EmailVerificationToken
+	err := database.GetDB().Where("user_id = ?", user.ID).Order("created_at DESC").First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch verification token"})
+		return
+	}
+	if err == nil {
+		if wait := last.CreatedAt.Add(emailVerificationCooldown).Sub(now); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another verification email"})
+			return
+		}
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return sendVerificationEmail(tx, &user, user.Email, now)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Verification email sent",
+	})
+}
+
+// RequireVerifiedEmail はメールアドレスが未確認のユーザーを制限するミドルウェアを返します
+// 設定したポリシーがminPolicy以上に厳しい場合のみ制限します（auth.Middlewareの後に使用）
+func RequireVerifiedEmail(minPolicy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verificationPolicyRanks[emailVerificationPolicy] < verificationPolicyRanks[minPolicy] {
+			c.Next()
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		if err := database.GetDB().Select("user_id", "email_verified_at").Where("user_id = ?", userID).First(&user).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Email address must be verified to use this feature"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// initEmailVerification は確認メールの設定を反映します
+func initEmailVerification(ttl, cooldown time.Duration, policy string) {
+	if ttl > 0 {
+		emailVerificationTTL = ttl
+	}
+	if cooldown > 0 {
+		emailVerificationCooldown = cooldown
+	}
+	if _, ok := verificationPolicyRanks[policy]; ok {
+		emailVerificationPolicy = policy
+	} else if policy != "" {
+		log.Printf("Warning: Unknown EMAIL_VERIFICATION_POLICY %q. Using %s.", policy, emailVerificationPolicy)
+	}
+}
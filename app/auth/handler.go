@@ -1,13 +1,25 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"backend/database"
+	"backend/mail"
 	"backend/models"
+	"backend/srs"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // RegisterHandler はユーザー登録ハンドラーです
@@ -46,33 +58,34 @@ func RegisterHandler(c *gin.Context) {
 
 	// ユーザーをデータベースに挿入
 	user := models.User{
-		Username:        req.Username,
-		Email:           req.Email,
-		PasswordHash:    hashedPassword,
-		PreferredAccent: preferredAccent,
-		StudyLevel:      studyLevel,
-		CreatedAt:       time.Now(),
+		Username:            req.Username,
+		Email:               req.Email,
+		PasswordHash:        hashedPassword,
+		PreferredAccent:     preferredAccent,
+		StudyLevel:          studyLevel,
+		SchedulingAlgorithm: srs.DefaultAlgorithm,
+		CreatedAt:           time.Now(),
 	}
 
-	if err := database.GetDB().Create(&user).Error; err != nil {
+	// ユーザーの作成と確認メールの送信
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return sendVerificationEmail(tx, &user, user.Email, user.CreatedAt)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	// JWTトークンを生成
-	token, err := GenerateJWT(user.ID, user.Username)
+	// アクセストークンとリフレッシュトークンを生成
+	response, err := newAuthResponse(c, "User created successfully", &user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// レスポンスを返す
-	response := models.AuthResponse{
-		Message: "User created successfully",
-		Token:   token,
-		User:    user.ToResponse(),
-	}
-
 	c.JSON(http.StatusCreated, response)
 }
 
@@ -84,39 +97,64 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	// ユーザーをデータベースから取得
-	var user models.User
-	err := database.GetDB().Where("username = ?", req.Username).First(&user).Error
+	// ロック中はパスワードを検証せずに拒否する
+	now := clock()
+	ip := c.ClientIP()
+	wait, err := loginLimiter.RetryAfter(req.Username, ip, now)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts. Please try again later"})
 		return
 	}
 
-	// パスワードを検証
-	if !CheckPasswordHash(req.Password, user.PasswordHash) {
+	// ユーザーをデータベースから取得し、パスワードを検証
+	var user models.User
+	err = database.GetDB().Where("username = ?", req.Username).First(&user).Error
+	var passwordOK, needsRehash bool
+	if err == nil {
+		passwordOK, needsRehash = VerifyPassword(req.Password, user.PasswordHash)
+	}
+	if !passwordOK {
+		if err := loginLimiter.RecordFailure(req.Username, ip, now); err != nil {
+			log.Printf("Warning: Failed to record login failure: %v", err)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// 以前のアルゴリズム・パラメータのハッシュを現在の設定で作り直す
+	if needsRehash {
+		if hashedPassword, err := HashPassword(req.Password); err != nil {
+			log.Printf("Warning: Failed to rehash password for user %d: %v", user.ID, err)
+		} else if err := database.GetDB().Model(&user).Where("password_hash = ?", user.PasswordHash).Update("password_hash", hashedPassword).Error; err != nil {
+			log.Printf("Warning: Failed to update password hash for user %d: %v", user.ID, err)
+		}
+	}
+
+	// 2要素認証が有効な場合はMFAトークンを返し、失敗回数は2要素目の成功までリセットしない
+	if user.MFAEnabled {
+		respondMFARequired(c, &user, now)
+		return
+	}
+	if err := loginLimiter.RecordSuccess(req.Username); err != nil {
+		log.Printf("Warning: Failed to reset login attempts: %v", err)
+	}
+
 	// last_loginを更新
-	now := time.Now()
 	user.LastLogin = &now
 	database.GetDB().Model(&user).Update("last_login", now)
 
-	// JWTトークンを生成
-	token, err := GenerateJWT(user.ID, user.Username)
+	// アクセストークンとリフレッシュトークンを生成
+	response, err := newAuthResponse(c, "Login successful", &user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// レスポンスを返す
-	response := models.AuthResponse{
-		Message: "Login successful",
-		Token:   token,
-		User:    user.ToResponse(),
-	}
-
 	c.JSON(http.StatusOK, response)
 }
 
@@ -148,8 +186,9 @@ func ProfileHandler(c *gin.Context) {
 
 // UpdateProfileRequest はプロフィール更新リクエスト構造体です
 type UpdateProfileRequest struct {
-	PreferredAccent string `json:"preferred_accent,omitempty"`
-	StudyLevel      string `json:"study_level,omitempty"`
+	PreferredAccent     string `json:"preferred_accent,omitempty"`
+	StudyLevel          string `json:"study_level,omitempty"`
+	SchedulingAlgorithm string `json:"scheduling_algorithm,omitempty"`
 }
 
 // UpdateProfileHandler はプロフィール更新ハンドラーです（認証が必要）
@@ -199,8 +238,28 @@ func UpdateProfileHandler(c *gin.Context) {
 		user.StudyLevel = req.StudyLevel
 	}
 
+	var scheduler srs.Scheduler
+	if req.SchedulingAlgorithm != "" && req.SchedulingAlgorithm != user.SchedulingAlgorithm {
+		scheduler, err = srs.Get(req.SchedulingAlgorithm)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduling_algorithm. Must be SM2, LEITNER, or FSRS"})
+			return
+		}
+		updateData["scheduling_algorithm"] = req.SchedulingAlgorithm
+		user.SchedulingAlgorithm = req.SchedulingAlgorithm
+	}
+
 	if len(updateData) > 0 {
-		err = database.GetDB().Model(&user).Updates(updateData).Error
+		err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(updateData).Error; err != nil {
+				return err
+			}
+			// アルゴリズムを変更した場合は既存の学習状態を引き継ぐ
+			if scheduler != nil {
+				return migrateProgress(tx, user.ID, scheduler)
+			}
+			return nil
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
 			return
@@ -212,3 +271,216 @@ func UpdateProfileHandler(c *gin.Context) {
 		"user":    user.ToResponse(),
 	})
 }
+
+// migrateProgress はユーザーの全ての学習状態を指定したアルゴリズムの状態に変換します
+func migrateProgress(tx *gorm.DB, userID uint, scheduler srs.Scheduler) error {
+	var progresses []models.WordProgress
+	if err := tx.Where("user_id = ? AND algorithm <> ?", userID, scheduler.Name()).Find(&progresses).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range progresses {
+		progresses[i].ApplyCard(srs.Prepare(scheduler, progresses[i].Card()))
+		progresses[i].UpdatedAt = now
+		if err := tx.Save(&progresses[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChangePasswordHandler はパスワード変更ハンドラーです（認証が必要）
+// 変更後は現在のセッション以外の全てのセッションとリフレッシュトークン、全てのAPIキーを失効させ、
+// 現在のセッション用の新しいアクセストークンを返します
+func ChangePasswordHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().Where("user_id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// 現在のパスワードを検証
+	if !CheckPasswordHash(req.CurrentPassword, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	hashedPassword, err := HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// パスワードの変更と他の端末のトークンの失効は同じトランザクションで行う
+	now := time.Now()
+	var version int
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&user).Update("password_hash", hashedPassword).Error
+		if err != nil {
+			return err
+		}
+		version, err = revokeAllTx(tx, user.ID, claims.SessionID, now)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	revocations.CacheTokenVersion(user.ID, version, now)
+
+	// 現在のセッションのアクセストークンを再発行
+	user.TokenVersion = version
+	token, err := GenerateJWT(&user, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Password changed successfully",
+		"token":      token,
+		"expires_in": int64(accessTokenTTL.Seconds()),
+	})
+}
+
+// ChangeEmailHandler はメールアドレス変更ハンドラーです（認証が必要）
+// 新しいメールアドレスに確認メールを送信し、確認されるまでメールアドレスは変更しません
+func ChangeEmailHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().Where("user_id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// 現在のパスワードを検証
+	if !CheckPasswordHash(req.CurrentPassword, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	if strings.EqualFold(req.Email, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New email must be different from the current email"})
+		return
+	}
+	if taken, err := emailTaken(database.GetDB(), req.Email, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email"})
+		return
+	} else if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return sendVerificationEmail(tx, &user, req.Email, time.Now())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	// 現在のメールアドレスにも変更を通知する
+	sendMailAsync(mail.Message{
+		To:      user.Email,
+		Subject: "Email address change requested",
+		Body: fmt.Sprintf("Hello %s,\n\nA request was made to change the email address of your account to %s. The change takes effect after the new address is verified.\n\nIf you did not make this request, please change your password immediately.\n",
+			user.Username, req.Email),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Verification email sent to the new address",
+	})
+}
+
+// emailTaken はメールアドレスが他のユーザーに使用されているかを確認します
+func emailTaken(tx *gorm.DB, email string, excludeUserID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&models.User{}).Where("LOWER(email) = LOWER(?) AND user_id <> ?", email, excludeUserID).Count(&count).Error
+	return count > 0, err
+}
+
+// createVerifiedUser は所有を確認済みのメールアドレスでパスワードなしのユーザーを作成します（外部プロバイダー、ログインリンク）
+// パスワードは推測できない値にし、必要になった場合はパスワードリセットで設定してもらいます
+func createVerifiedUser(tx *gorm.DB, user *models.User, email string, now time.Time) error {
+	username, err := availableUsername(tx, email)
+	if err != nil {
+		return err
+	}
+	password, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	*user = models.User{
+		Username:            username,
+		Email:               email,
+		PasswordHash:        hashedPassword,
+		PreferredAccent:     "US",
+		StudyLevel:          "BEGINNER",
+		SchedulingAlgorithm: srs.DefaultAlgorithm,
+		CreatedAt:           now,
+		EmailVerifiedAt:     &now,
+	}
+	return tx.Create(user).Error
+}
+
+// availableUsername はメールアドレスのローカル部から使用されていないユーザー名を作ります
+// 既に使用されている場合はランダムな接尾辞を付けます
+func availableUsername(tx *gorm.DB, email string) (string, error) {
+	local, _, _ := strings.Cut(email, "@")
+	base := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-') {
+			return r
+		}
+		return -1
+	}, local)
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("LOWER(username) = LOWER(?)", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", err
+		}
+		candidate = base + "_" + hex.EncodeToString(suffix)
+	}
+	return "", errors.New("failed to find an available username")
+}
@@ -1,55 +1,143 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"backend/config"
+	"backend/models"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // JWTクレーム構造体
+// RegisteredClaims.IDにはトークンごとに一意なjtiを設定し、失効の管理に使用します
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	TokenVersion int    `json:"ver"`            // 発行時のユーザーのトークンバージョン（全端末ログアウトで無効になる）
+	SessionID    uint   `json:"sid,omitempty"`  // ログインした端末のセッション
+	Role         string `json:"role,omitempty"` // 発行時のユーザーの役割
 	jwt.RegisteredClaims
 }
 
-var jwtSecret []byte
+var (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+	tokenIssuer     string
+	tokenAudience   string
+	tokenLeeway     = 30 * time.Second
+)
+
+// アクセストークンの検証エラー（Middlewareが理由ごとにWWW-Authenticateヘッダーを返す）
+var (
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenInvalidSignature = errors.New("token signature is invalid")
+	ErrTokenExpired          = errors.New("token has expired")
+	ErrTokenNotYetValid      = errors.New("token is not valid yet")
+	ErrTokenInvalidAudience  = errors.New("token audience is invalid")
+	ErrTokenInvalidIssuer    = errors.New("token issuer is invalid")
+	ErrTokenInvalidClaims    = errors.New("token claims are invalid")
+)
 
-// InitJWT はJWT秘密鍵を初期化します
-func InitJWT(secret string) {
-	jwtSecret = []byte(secret)
+// InitJWT は署名鍵とトークンの有効期間を初期化します
+func InitJWT(cfg config.JWTConfig) error {
+	ring, err := loadKeyRing(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+	keys = ring
+	tokenIssuer = cfg.Issuer
+	tokenAudience = cfg.Audience
+	if cfg.Leeway > 0 {
+		tokenLeeway = cfg.Leeway
+	}
+	if cfg.AccessTokenTTL > 0 {
+		accessTokenTTL = cfg.AccessTokenTTL
+	}
+	if cfg.RefreshTokenTTL > 0 {
+		refreshTokenTTL = cfg.RefreshTokenTTL
+	}
+	revocations = newRevocationStore(cfg.RevocationCacheTTL)
+	sessions = newSessionTracker(cfg.RevocationCacheTTL, cfg.SessionTouchInterval)
+	return nil
 }
 
-// GenerateJWT はJWTトークンを生成します
-func GenerateJWT(userID uint, username string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateJWT はユーザーのセッションの短命なアクセストークンを生成します
+func GenerateJWT(user *models.User, sessionID uint) (string, error) {
+	jti, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:       user.ID,
+		Username:     user.Username,
+		TokenVersion: user.TokenVersion,
+		SessionID:    sessionID,
+		Role:         user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        jti,
+			Issuer:    tokenIssuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
 		},
 	}
+	if tokenAudience != "" {
+		claims.Audience = jwt.ClaimStrings{tokenAudience}
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return keys.sign(claims)
 }
 
 // ValidateJWT はJWTトークンを検証します
+// 署名アルゴリズムは鍵ごとに固定し、exp・nbf・iatはtokenLeewayの時計のずれを許容して検証します。
+// iss・audは設定されている場合のみ必須にします。エラーはErrToken*のいずれかを返します
 func ValidateJWT(tokenString string) (*Claims, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(keys.methods()),
+		jwt.WithLeeway(tokenLeeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+	if tokenIssuer != "" {
+		options = append(options, jwt.WithIssuer(tokenIssuer))
+	}
+	if tokenAudience != "" {
+		options = append(options, jwt.WithAudience(tokenAudience))
+	}
 
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc, options...)
 	if err != nil {
-		return nil, err
+		return nil, classifyTokenError(err)
 	}
-
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+	if !token.Valid || claims.ID == "" {
+		return nil, ErrTokenInvalidClaims
 	}
 
 	return claims, nil
 }
+
+// classifyTokenError はjwtライブラリのエラーを検証エラーの理由に分類します
+func classifyTokenError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenInvalidSignature
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenInvalidAudience
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenInvalidIssuer
+	default:
+		return ErrTokenInvalidClaims
+	}
+}
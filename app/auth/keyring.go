@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"backend/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits はRS256の鍵の最小サイズです
+const minRSAKeyBits = 2048
+
+// keys はアクセストークンの署名・検証に使う鍵です
+var keys = &keyRing{}
+
+// errUnknownKey はトークンのkidに対応する鍵がない場合のエラーです
+var errUnknownKey = errors.New("unknown signing key")
+
+// signingKey はkidで識別する非対称鍵です
+// privateがnilの鍵はローテーションで退役した検証専用の鍵です
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// keyRing はアクセストークンの鍵の集合です
+// 非対称鍵が設定されている場合はcurrentで署名し、kidで選んだ鍵で検証します。
+// 退役した鍵は、その鍵で署名されたトークンの有効期限が切れるまで設定に残してください
+type keyRing struct {
+	current *signingKey
+	byID    map[string]*signingKey
+	secret  []byte // 非対称鍵が未設定の場合、またはlegacyHMACUntilまでkidのない以前のトークンの検証に使うHMAC秘密鍵
+	// 非対称鍵に切り替える前に発行したHS256のトークンを受け付ける期限（ゼロ値の場合は受け付けない）
+	legacyHMACUntil time.Time
+}
+
+// JWK はJSON Web Key（RFC 7517）の公開鍵です
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA
+	E         string `json:"e,omitempty"`   // RSA
+	Curve     string `json:"crv,omitempty"` // OKP
+	X         string `json:"x,omitempty"`   // OKP
+}
+
+// JWKS はJSON Web Key Setです
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// loadKeyRing は設定のPEMファイルから鍵を読み込みます
+func loadKeyRing(cfg config.JWTConfig) (*keyRing, error) {
+	ring := &keyRing{
+		byID:   make(map[string]*signingKey),
+		secret: []byte(cfg.Secret),
+	}
+
+	if cfg.SigningKeyFile != "" {
+		key, err := loadKeyFile(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if key.private == nil {
+			return nil, fmt.Errorf("%s: signing key must be a private key", cfg.SigningKeyFile)
+		}
+		ring.current = key
+		ring.byID[key.id] = key
+	}
+
+	// 切り替え前のトークンの有効期限が切れた後もHS256を受け付けると、
+	// 秘密鍵が漏洩した場合に非対称鍵に切り替えても偽造を防げないため、期限を最長でアクセストークンの有効期間にする
+	if ring.current != nil && len(ring.secret) > 0 && !cfg.LegacyHMACUntil.IsZero() {
+		ttl := accessTokenTTL
+		if cfg.AccessTokenTTL > 0 {
+			ttl = cfg.AccessTokenTTL
+		}
+		ring.legacyHMACUntil = cfg.LegacyHMACUntil
+		if limit := time.Now().Add(ttl); ring.legacyHMACUntil.After(limit) {
+			log.Printf("Warning: JWT_LEGACY_HMAC_UNTIL is more than the access token TTL away. Using %s.", limit.Format(time.RFC3339))
+			ring.legacyHMACUntil = limit
+		}
+	}
+
+	for _, path := range cfg.RetiredKeyFiles {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// 退役した鍵は検証にのみ使用する
+		key.private = nil
+		if _, exists := ring.byID[key.id]; !exists {
+			ring.byID[key.id] = key
+		}
+	}
+
+	return ring, nil
+}
+
+// loadKeyFile はPEMファイルからRSAまたはEd25519の鍵を読み込みます
+// kidは公開鍵のJWKサムプリント（RFC 7638）から求めるため、設定で指定する必要はありません
+func loadKeyFile(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block type %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key := &signingKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T (must be RSA or Ed25519)", path, parsed)
+	}
+	if pub, ok := key.public.(*rsa.PublicKey); ok && pub.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("%s: RSA key must be at least %d bits", path, minRSAKeyBits)
+	}
+
+	key.id = thumbprint(key.jwk())
+	return key, nil
+}
+
+// jwk は公開鍵のJWKを返します
+func (k *signingKey) jwk() JWK {
+	jwk := JWK{Use: "sig", Algorithm: k.method.Alg(), KeyID: k.id}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// thumbprint はJWKサムプリント（RFC 7638、SHA-256）を返します
+func thumbprint(jwk JWK) string {
+	// 必須メンバーのみを辞書順に並べたJSON
+	var members map[string]string
+	if jwk.KeyType == "RSA" {
+		members = map[string]string{"e": jwk.E, "kty": jwk.KeyType, "n": jwk.N}
+	} else {
+		members = map[string]string{"crv": jwk.Curve, "kty": jwk.KeyType, "x": jwk.X}
+	}
+	data, _ := json.Marshal(members) // mapのキーは辞書順で出力される
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// sign はクレームに署名します。非対称鍵が未設定の場合はHS256で署名します
+func (r *keyRing) sign(claims jwt.Claims) (string, error) {
+	if r.current == nil {
+		if len(r.secret) == 0 {
+			return "", errors.New("no signing key configured")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(r.secret)
+	}
+
+	token := jwt.NewWithClaims(r.current.method, claims)
+	token.Header["kid"] = r.current.id
+	return token.SignedString(r.current.private)
+}
+
+// keyFunc はトークンのkidとアルゴリズムに対応する検証鍵を返します
+// 鍵ごとにアルゴリズムを固定し、ヘッダーのalgで別の方式に切り替えられないようにします
+func (r *keyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || token.Method.Alg() != jwt.SigningMethodHS256.Alg() || !r.acceptsHMAC(time.Now()) {
+			return nil, errUnknownKey
+		}
+		return r.secret, nil
+	}
+
+	key, ok := r.byID[kid]
+	if !ok {
+		return nil, errUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// acceptsHMAC はkidのないHS256のトークンを受け付けるかを返します
+// 非対称鍵が設定されている場合はlegacyHMACUntilまでのみ受け付けます
+func (r *keyRing) acceptsHMAC(now time.Time) bool {
+	if len(r.secret) == 0 {
+		return false
+	}
+	return r.current == nil || now.Before(r.legacyHMACUntil)
+}
+
+// methods は検証で受け付ける署名アルゴリズムです
+func (r *keyRing) methods() []string {
+	var algs []string
+	seen := make(map[string]bool)
+	if r.acceptsHMAC(time.Now()) {
+		algs = append(algs, jwt.SigningMethodHS256.Alg())
+		seen[jwt.SigningMethodHS256.Alg()] = true
+	}
+	for _, key := range r.byID {
+		if alg := key.method.Alg(); !seen[alg] {
+			algs = append(algs, alg)
+			seen[alg] = true
+		}
+	}
+	return algs
+}
+
+// jwks は検証に使う全ての公開鍵を返します（署名中の鍵が先頭）
+func (r *keyRing) jwks() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	if r.current != nil {
+		set.Keys = append(set.Keys, r.current.jwk())
+	}
+	retired := make([]JWK, 0, len(r.byID))
+	for id, key := range r.byID {
+		if r.current != nil && id == r.current.id {
+			continue
+		}
+		retired = append(retired, key.jwk())
+	}
+	sort.Slice(retired, func(i, j int) bool { return retired[i].KeyID < retired[j].KeyID })
+	set.Keys = append(set.Keys, retired...)
+	return set
+}
+
+// JWKSHandler はアクセストークンの検証用の公開鍵（JWKS）を返すハンドラーです
+// 他のサービスは秘密鍵を持たずに、kidで選んだ公開鍵でトークンを検証できます
+func JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keys.jwks())
+}
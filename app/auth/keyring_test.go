@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"backend/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testHMACSecret = "test-hmac-secret"
+
+// writeEd25519Key はEd25519の秘密鍵をPEMファイルに書き出してパスを返します
+func writeEd25519Key(t *testing.T) string {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setKeyRing は鍵を読み込んでkeysに設定し、テスト終了時に元に戻します
+func setKeyRing(t *testing.T, cfg config.JWTConfig) *keyRing {
+	t.Helper()
+	ring, err := loadKeyRing(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := keys
+	t.Cleanup(func() { keys = original })
+	keys = ring
+	return ring
+}
+
+// testClaims は検証に通る最小限のクレームです
+func testClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "test-jti",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+// signLegacyHMAC はkidのないHS256のトークン（非対称鍵に切り替える前の形式）を作成します
+func signLegacyHMAC(t *testing.T) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte(testHMACSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestLegacyHMACTokens(t *testing.T) {
+	keyFile := writeEd25519Key(t)
+
+	tests := []struct {
+		name    string
+		cfg     config.JWTConfig
+		wantErr error
+	}{
+		{"HMAC only", config.JWTConfig{Secret: testHMACSecret}, nil},
+		{"asymmetric key without cutoff", config.JWTConfig{Secret: testHMACSecret, SigningKeyFile: keyFile}, ErrTokenInvalidSignature},
+		{"before cutoff", config.JWTConfig{
+			Secret: testHMACSecret, SigningKeyFile: keyFile, LegacyHMACUntil: time.Now().Add(5 * time.Minute),
+		}, nil},
+		{"after cutoff", config.JWTConfig{
+			Secret: testHMACSecret, SigningKeyFile: keyFile, LegacyHMACUntil: time.Now().Add(-time.Second),
+		}, ErrTokenInvalidSignature},
+		{"asymmetric key without secret", config.JWTConfig{
+			SigningKeyFile: keyFile, LegacyHMACUntil: time.Now().Add(5 * time.Minute),
+		}, ErrTokenInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setKeyRing(t, tt.cfg)
+			if _, err := ValidateJWT(signLegacyHMAC(t)); err != tt.wantErr {
+				t.Errorf("ValidateJWT() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLegacyHMACUntilIsCapped(t *testing.T) {
+	before := time.Now()
+	ring := setKeyRing(t, config.JWTConfig{
+		Secret:          testHMACSecret,
+		SigningKeyFile:  writeEd25519Key(t),
+		AccessTokenTTL:  15 * time.Minute,
+		LegacyHMACUntil: before.AddDate(1, 0, 0),
+	})
+
+	if limit := time.Now().Add(15 * time.Minute); ring.legacyHMACUntil.After(limit) {
+		t.Errorf("legacyHMACUntil = %v, want at most %v", ring.legacyHMACUntil, limit)
+	}
+	if !ring.legacyHMACUntil.After(before) {
+		t.Errorf("legacyHMACUntil = %v, want after %v", ring.legacyHMACUntil, before)
+	}
+}
+
+func TestAsymmetricTokens(t *testing.T) {
+	ring := setKeyRing(t, config.JWTConfig{
+		Secret:          testHMACSecret,
+		SigningKeyFile:  writeEd25519Key(t),
+		LegacyHMACUntil: time.Now().Add(5 * time.Minute),
+	})
+
+	signed, err := ring.sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(signed); err != nil {
+		t.Errorf("ValidateJWT(EdDSA) error = %v", err)
+	}
+
+	// 非対称鍵のkidを指定したHS256のトークンは受け付けない
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	token.Header["kid"] = ring.current.id
+	confused, err := token.SignedString([]byte(testHMACSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(confused); err != ErrTokenInvalidSignature {
+		t.Errorf("ValidateJWT(HS256 with kid) error = %v, want %v", err, ErrTokenInvalidSignature)
+	}
+}
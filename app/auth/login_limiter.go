@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/config"
+	"backend/database"
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// loginLimiter はLoginHandlerが参照するログイン試行回数の制限です
+var loginLimiter = NewLoginLimiter(NewMemoryAttemptStore(), config.LoginLimitConfig{})
+
+// AttemptState はキーごとのログイン失敗の状態です
+type AttemptState struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// AttemptStore はログイン失敗回数の保存先のインターフェースです
+type AttemptStore interface {
+	// Get はキーの状態を返します（記録がない場合はゼロ値）
+	Get(key string) (AttemptState, error)
+	// AddFailure は失敗回数を1増やして新しい回数を返します
+	// 最後の失敗からwindow以上経過している場合は1から数え直します
+	AddFailure(key string, now time.Time, window time.Duration) (int, error)
+	// Lock はキーをuntilまでロックします
+	Lock(key string, until time.Time) error
+	// Reset はキーの記録を削除します
+	Reset(key string) error
+	// Prune は最後の失敗がbefore以前でロックも解除されている記録を削除します
+	Prune(before, now time.Time) error
+}
+
+// LoginLimiter はユーザー名・IPアドレスごとのログイン失敗回数に応じて
+// 指数的に長くなるロックをかけます
+type LoginLimiter struct {
+	store         AttemptStore
+	maxAttempts   int
+	ipMaxAttempts int
+	baseDelay     time.Duration
+	maxLockout    time.Duration
+	window        time.Duration
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewLoginLimiter はログイン試行回数の制限を作成します（未設定の値は既定値を使用）
+func NewLoginLimiter(store AttemptStore, cfg config.LoginLimitConfig) *LoginLimiter {
+	l := &LoginLimiter{
+		store:         store,
+		maxAttempts:   5,
+		ipMaxAttempts: 20,
+		baseDelay:     time.Second,
+		maxLockout:    15 * time.Minute,
+		window:        15 * time.Minute,
+	}
+	if cfg.MaxAttempts > 0 {
+		l.maxAttempts = cfg.MaxAttempts
+	}
+	if cfg.IPMaxAttempts > 0 {
+		l.ipMaxAttempts = cfg.IPMaxAttempts
+	}
+	if cfg.BaseDelay > 0 {
+		l.baseDelay = cfg.BaseDelay
+	}
+	if cfg.MaxLockout > 0 {
+		l.maxLockout = cfg.MaxLockout
+	}
+	if cfg.Window > 0 {
+		l.window = cfg.Window
+	}
+	return l
+}
+
+// usernameKey はユーザー名の記録のキーです
+func usernameKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// ipKey はIPアドレスの記録のキーです
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// RetryAfter はユーザー名・IPアドレスのどちらかがロック中の場合に残り時間を返します
+func (l *LoginLimiter) RetryAfter(username, ip string, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range []string{usernameKey(username), ipKey(ip)} {
+		state, err := l.store.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		if remaining := state.LockedUntil.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// RecordFailure はログイン失敗を記録し、回数が上限を超えていればロックします
+func (l *LoginLimiter) RecordFailure(username, ip string, now time.Time) error {
+	keys := []struct {
+		key         string
+		maxAttempts int
+	}{
+		{usernameKey(username), l.maxAttempts},
+		{ipKey(ip), l.ipMaxAttempts},
+	}
+	for _, k := range keys {
+		failures, err := l.store.AddFailure(k.key, now, l.window)
+		if err != nil {
+			return err
+		}
+		if delay := l.lockout(failures, k.maxAttempts); delay > 0 {
+			if err := l.store.Lock(k.key, now.Add(delay)); err != nil {
+				return err
+			}
+		}
+	}
+	l.prune(now)
+	return nil
+}
+
+// RecordSuccess はログイン成功時にユーザー名の失敗回数をリセットします
+// IPアドレスの記録は、攻撃者が自分のアカウントでログインしてリセットできないよう残します
+func (l *LoginLimiter) RecordSuccess(username string) error {
+	return l.store.Reset(usernameKey(username))
+}
+
+// lockout は失敗回数に対するロック期間です
+// maxAttempts回目の失敗でbaseDelay、以降は失敗ごとに2倍にしてmaxLockoutで打ち止めにします
+func (l *LoginLimiter) lockout(failures, maxAttempts int) time.Duration {
+	if failures < maxAttempts {
+		return 0
+	}
+	delay := l.baseDelay
+	for i := maxAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= l.maxLockout {
+			return l.maxLockout
+		}
+	}
+	return delay
+}
+
+// prune は古い記録をwindowごとに1回削除します
+func (l *LoginLimiter) prune(now time.Time) {
+	l.mu.Lock()
+	if now.Sub(l.lastPrune) < l.window {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPrune = now
+	l.mu.Unlock()
+
+	if err := l.store.Prune(now.Add(-l.window), now); err != nil {
+		log.Printf("Warning: Failed to prune login attempts: %v", err)
+	}
+}
+
+// MemoryAttemptStore はログイン失敗回数をメモリに保持します（単一サーバー・テスト用）
+type MemoryAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]AttemptState
+}
+
+// NewMemoryAttemptStore はメモリ上の保存先を作成します
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{entries: make(map[string]AttemptState)}
+}
+
+// Get はキーの状態を返します
+func (s *MemoryAttemptStore) Get(key string) (AttemptState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key], nil
+}
+
+// AddFailure は失敗回数を1増やします
+func (s *MemoryAttemptStore) AddFailure(key string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.entries[key]
+	if now.Sub(state.LastFailureAt) >= window {
+		state.Failures = 0
+	}
+	state.Failures++
+	state.LastFailureAt = now
+	s.entries[key] = state
+	return state.Failures, nil
+}
+
+// Lock はキーをuntilまでロックします
+func (s *MemoryAttemptStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.entries[key]
+	state.LockedUntil = until
+	s.entries[key] = state
+	return nil
+}
+
+// Reset はキーの記録を削除します
+func (s *MemoryAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Prune は古い記録を削除します
+func (s *MemoryAttemptStore) Prune(before, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, state := range s.entries {
+		if !state.LastFailureAt.After(before) && !state.LockedUntil.After(now) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
+
+// DBAttemptStore はログイン失敗回数をデータベースに保存します（複数サーバー構成の本番用）
+type DBAttemptStore struct{}
+
+// Get はキーの状態を返します
+func (DBAttemptStore) Get(key string) (AttemptState, error) {
+	var attempt models.LoginAttempt
+	err := database.GetDB().Where("key = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return AttemptState{}, nil
+	}
+	if err != nil {
+		return AttemptState{}, err
+	}
+	state := AttemptState{Failures: attempt.Failures, LastFailureAt: attempt.LastFailureAt}
+	if attempt.LockedUntil != nil {
+		state.LockedUntil = *attempt.LockedUntil
+	}
+	return state, nil
+}
+
+// AddFailure は失敗回数を1増やします（同時に失敗しても数え漏れがないよう1文で更新）
+func (DBAttemptStore) AddFailure(key string, now time.Time, window time.Duration) (int, error) {
+	var failures int
+	err := database.GetDB().Raw(`
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at <= ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`, key, now, now.Add(-window)).Scan(&failures).Error
+	return failures, err
+}
+
+// Lock はキーをuntilまでロックします
+func (DBAttemptStore) Lock(key string, until time.Time) error {
+	return database.GetDB().Model(&models.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+// Reset はキーの記録を削除します
+func (DBAttemptStore) Reset(key string) error {
+	return database.GetDB().Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+// Prune は古い記録を削除します
+func (DBAttemptStore) Prune(before, now time.Time) error {
+	return database.GetDB().
+		Where("last_failure_at <= ? AND (locked_until IS NULL OR locked_until <= ?)", before, now).
+		Delete(&models.LoginAttempt{}).Error
+}
+
+// initLoginLimiter はログイン試行回数の制限の設定を反映します
+func initLoginLimiter(cfg config.LoginLimitConfig) {
+	var store AttemptStore
+	switch strings.ToLower(cfg.Store) {
+	case "memory":
+		store = NewMemoryAttemptStore()
+	case "postgres", "":
+		store = DBAttemptStore{}
+	default:
+		log.Printf("Warning: Unknown LOGIN_LIMIT_STORE %q. Using postgres.", cfg.Store)
+		store = DBAttemptStore{}
+	}
+	loginLimiter = NewLoginLimiter(store, cfg)
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"backend/config"
+)
+
+var limiterNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestLimiter() (*LoginLimiter, *MemoryAttemptStore) {
+	store := NewMemoryAttemptStore()
+	return NewLoginLimiter(store, config.LoginLimitConfig{
+		MaxAttempts:   3,
+		IPMaxAttempts: 5,
+		BaseDelay:     time.Second,
+		MaxLockout:    time.Minute,
+		Window:        15 * time.Minute,
+	}), store
+}
+
+func TestLoginLimiterLockout(t *testing.T) {
+	l := NewLoginLimiter(NewMemoryAttemptStore(), config.LoginLimitConfig{})
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{4, 0},
+		{5, time.Second},
+		{6, 2 * time.Second},
+		{7, 4 * time.Second},
+		{14, 512 * time.Second},
+		{15, 15 * time.Minute}, // 1024秒は上限で打ち止め
+		{100, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := l.lockout(tt.failures, 5); got != tt.want {
+			t.Errorf("lockout(%d, 5) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginLimiterRecordFailure(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	wants := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second}
+	for i, want := range wants {
+		if err := l.RecordFailure("Alice", "192.0.2.1", limiterNow); err != nil {
+			t.Fatal(err)
+		}
+		got, err := l.RetryAfter("alice", "192.0.2.2", limiterNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("after %d failures RetryAfter = %v, want %v", i+1, got, want)
+		}
+	}
+
+	// ロック期間が過ぎれば再試行できる
+	if got, _ := l.RetryAfter("alice", "192.0.2.2", limiterNow.Add(4*time.Second)); got != 0 {
+		t.Errorf("RetryAfter after lockout = %v, want 0", got)
+	}
+}
+
+func TestLoginLimiterWindowReset(t *testing.T) {
+	l, store := newTestLimiter()
+
+	for i := 0; i < 2; i++ {
+		if err := l.RecordFailure("alice", "192.0.2.1", limiterNow); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"within window", limiterNow.Add(15*time.Minute - time.Second), 3},
+		{"window elapsed since last failure", limiterNow.Add(30*time.Minute - time.Second), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := l.RecordFailure("alice", "192.0.2.1", tt.at); err != nil {
+				t.Fatal(err)
+			}
+			state, _ := store.Get(usernameKey("alice"))
+			if state.Failures != tt.want {
+				t.Errorf("Failures = %d, want %d", state.Failures, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginLimiterRecordSuccessKeepsIP(t *testing.T) {
+	l, store := newTestLimiter()
+
+	// IPアドレスの上限まで別々のユーザー名で失敗する
+	for _, username := range []string{"a", "b", "c", "d", "e"} {
+		if err := l.RecordFailure(username, "192.0.2.1", limiterNow); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.RecordSuccess("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, _ := store.Get(usernameKey("a")); state.Failures != 0 {
+		t.Errorf("username Failures = %d, want 0", state.Failures)
+	}
+	if state, _ := store.Get(ipKey("192.0.2.1")); state.Failures != 5 {
+		t.Errorf("ip Failures = %d, want 5", state.Failures)
+	}
+	if got, _ := l.RetryAfter("a", "192.0.2.1", limiterNow); got != time.Second {
+		t.Errorf("RetryAfter = %v, want %v", got, time.Second)
+	}
+}
+
+func TestMemoryAttemptStorePrune(t *testing.T) {
+	store := NewMemoryAttemptStore()
+	before := limiterNow.Add(-15 * time.Minute)
+
+	store.AddFailure("old", before, time.Minute)
+	store.AddFailure("old-locked", before, time.Minute)
+	store.Lock("old-locked", limiterNow.Add(time.Minute))
+	store.AddFailure("old-lock-expired", before, time.Minute)
+	store.Lock("old-lock-expired", limiterNow)
+	store.AddFailure("recent", limiterNow.Add(-time.Minute), time.Minute)
+
+	if err := store.Prune(before, limiterNow); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		kept bool
+	}{
+		{"old", false},
+		{"old-locked", true},
+		{"old-lock-expired", false},
+		{"recent", true},
+	}
+	for _, tt := range tests {
+		state, _ := store.Get(tt.key)
+		if kept := state.Failures > 0; kept != tt.kept {
+			t.Errorf("%s kept = %v, want %v", tt.key, kept, tt.kept)
+		}
+	}
+}
+
+func TestLoginLimiterPrunesOncePerWindow(t *testing.T) {
+	l, store := newTestLimiter()
+
+	store.AddFailure("stale", limiterNow.Add(-time.Hour), time.Minute)
+	if err := l.RecordFailure("alice", "192.0.2.1", limiterNow.Add(-20*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := store.Get("stale"); state.Failures != 0 {
+		t.Fatal("first failure did not prune the stale record")
+	}
+
+	// 前回からwindowが経過するまでは削除しない
+	store.AddFailure("stale", limiterNow.Add(-time.Hour), time.Minute)
+	if err := l.RecordFailure("alice", "192.0.2.1", limiterNow.Add(-10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := store.Get("stale"); state.Failures == 0 {
+		t.Error("pruned again within the window")
+	}
+
+	if err := l.RecordFailure("alice", "192.0.2.1", limiterNow); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := store.Get("stale"); state.Failures != 0 {
+		t.Error("did not prune after the window")
+	}
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/mail"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	magicLinkTTL        = 15 * time.Minute
+	magicLinkCooldown   = time.Minute
+	magicLinkAutoCreate = false
+)
+
+var (
+	// errInvalidMagicLink は存在しない・使用済み・期限切れのログインリンクのエラーです
+	errInvalidMagicLink = errors.New("invalid or expired magic link")
+	// errMagicLinkUnverified はメールアドレスが未確認のユーザーのログインリンクのエラーです
+	errMagicLinkUnverified = errors.New("magic link account email is not verified")
+)
+
+// RequestMagicLinkHandler はログインリンク申請ハンドラーです
+// ForgotPasswordHandlerと同様に、ログインできるメールアドレスかどうかはレスポンスから分かりません
+func RequestMagicLinkHandler(c *gin.Context) {
+	var req models.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := requestMagicLink(strings.TrimSpace(req.Email), time.Now()); err != nil {
+		log.Printf("Warning: Failed to create magic link: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the email address can be used to log in, a login link has been sent",
+	})
+}
+
+// requestMagicLink はログインリンクのトークンを発行してメールを送信します
+// 未登録のメールアドレスは自動作成が有効な場合のみ発行し、メールアドレスが未確認のユーザーと
+// 再送間隔内の申請には何もしません
+func requestMagicLink(email string, now time.Time) error {
+	var user models.User
+	err := database.GetDB().Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	found := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if !found && !magicLinkAutoCreate {
+		return nil
+	}
+	if found {
+		if user.EmailVerifiedAt == nil {
+			return nil
+		}
+		email = user.Email
+	}
+
+	var token string
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var recent int64
+		err := tx.Model(&models.MagicLinkToken{}).
+			Where("LOWER(email) = LOWER(?) AND created_at > ?", email, now.Add(-magicLinkCooldown)).
+			Count(&recent).Error
+		if err != nil || recent > 0 {
+			return err
+		}
+
+		record := models.MagicLinkToken{
+			Email:     email,
+			ExpiresAt: now.Add(magicLinkTTL),
+			CreatedAt: now,
+		}
+		if found {
+			record.UserID = &user.ID
+		}
+		token, err = issueSingleUseToken(tx, &record, &record.TokenHash, "LOWER(email) = LOWER(?)", email)
+		return err
+	})
+	if err != nil || token == "" {
+		return err
+	}
+
+	greeting := "Hello"
+	if found {
+		greeting += " " + user.Username
+	}
+	sendMailAsync(mail.Message{
+		To:      email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("%s,\n\nOpen the link below to log in:\n\n%s\n\nThis link expires in %s and can be used only once. If you did not request it, you can ignore this email.\n",
+			greeting, appLink("/magic-link", token), magicLinkTTL),
+	})
+	return nil
+}
+
+// ConsumeMagicLinkHandler はログインリンクでのログインハンドラーです
+// LoginHandlerと同じレスポンスを返し、2要素認証が有効な場合は2要素目を求めます
+func ConsumeMagicLinkHandler(c *gin.Context) {
+	var req models.ConsumeMagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := clock()
+	var (
+		user    models.User
+		created bool
+	)
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var token models.MagicLinkToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hashToken(req.Token)).First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidMagicLink
+		}
+		if err != nil {
+			return err
+		}
+		if token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+			return errInvalidMagicLink
+		}
+		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		// 未登録のメールアドレス宛てのリンクは、その後に登録されていればそのユーザーとしてログインする
+		query := tx.Where("LOWER(email) = LOWER(?)", token.Email)
+		if token.UserID != nil {
+			query = tx.Where("user_id = ?", *token.UserID)
+		}
+		err = query.First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if token.UserID != nil || !magicLinkAutoCreate {
+				return errInvalidMagicLink
+			}
+			created = true
+			return createVerifiedUser(tx, &user, token.Email, now)
+		}
+		if err != nil {
+			return err
+		}
+
+		// 発行後にメールアドレスを変更した場合、以前のアドレス宛てのリンクは使用できない
+		if !strings.EqualFold(user.Email, token.Email) {
+			return errInvalidMagicLink
+		}
+		// 未確認のアカウントは他人が先に登録したものかもしれないため、リンクを開けてもログインさせない
+		// （ログインさせて確認済みにすると、登録した人が設定したパスワードやセッションが残ったままになる）
+		if user.EmailVerifiedAt == nil {
+			return errMagicLinkUnverified
+		}
+		return nil
+	})
+	if errors.Is(err, errInvalidMagicLink) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login link"})
+		return
+	}
+	if errors.Is(err, errMagicLinkUnverified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address must be verified to log in with a login link. Log in with your password or reset it"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	// 2要素認証が有効な場合はログインリンクでも2要素目を求める
+	if user.MFAEnabled {
+		respondMFARequired(c, &user, now)
+		return
+	}
+
+	// last_loginを更新
+	user.LastLogin = &now
+	database.GetDB().Model(&user).Update("last_login", now)
+
+	message, status := "Login successful", http.StatusOK
+	if created {
+		message, status = "User created successfully", http.StatusCreated
+	}
+	response, err := newAuthResponse(c, message, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(status, response)
+}
+
+// initMagicLink はログインリンクの設定を反映します
+func initMagicLink(ttl, cooldown time.Duration, autoCreate bool) {
+	if ttl > 0 {
+		magicLinkTTL = ttl
+	}
+	if cooldown > 0 {
+		magicLinkCooldown = cooldown
+	}
+	magicLinkAutoCreate = autoCreate
+}
@@ -0,0 +1,489 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/totp"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 2要素認証のパラメータ
+const (
+	totpSkew          = 1  // 前後に許容するタイムステップ数
+	recoveryCodeCount = 10 // 発行するリカバリーコードの数
+	maxMFAAttempts    = 5  // 1回のログインで2要素目を間違えられる回数
+)
+
+// clock は2要素認証で使う現在時刻です（テストでは固定時刻に差し替える）
+var clock = time.Now
+
+var (
+	mfaIssuer       = "Tango"
+	mfaChallengeTTL = 5 * time.Minute
+)
+
+// errInvalidMFAChallenge は存在しない・使用済み・期限切れのMFAトークンのエラーです
+var errInvalidMFAChallenge = errors.New("invalid or expired mfa token")
+
+// recoveryCodeEncoding はリカバリーコードのエンコーディングです（小文字のBase32）
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// EnrollTOTPHandler は認証アプリの登録開始ハンドラーです（認証が必要）
+// 秘密鍵とotpauth URIを返し、ConfirmTOTPHandlerでコードを確認するまで有効にしません
+func EnrollTOTPHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req models.EnrollTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().Where("user_id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !CheckPasswordHash(req.Password, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+	if user.MFAEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	sealed, err := sealTOTPSecret(secret, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	// 登録途中のものがあれば新しい秘密鍵で置き換える
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.TOTPCredential{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.TOTPCredential{
+			UserID:    user.ID,
+			Secret:    sealed,
+			CreatedAt: clock(),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.EnrollTOTPResponse{
+		Secret: secret,
+		URI:    totp.URI(mfaIssuer, user.Username, secret),
+	})
+}
+
+// ConfirmTOTPHandler は認証アプリの登録確認ハンドラーです（認証が必要）
+// コードが正しければ2要素認証を有効にし、リカバリーコードを発行します
+func ConfirmTOTPHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req models.ConfirmTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var credential models.TOTPCredential
+	err := database.GetDB().Where("user_id = ? AND confirmed_at IS NULL", claims.UserID).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "TOTP enrollment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch TOTP enrollment"})
+		return
+	}
+
+	now := clock()
+	step, valid, err := validateTOTPCode(&credential, req.Code, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	var codes []string
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&credential).Updates(map[string]interface{}{
+			"confirmed_at":   now,
+			"last_used_step": step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("user_id = ?", claims.UserID).Update("mfa_enabled", true).Error; err != nil {
+			return err
+		}
+		codes, err = generateRecoveryCodes(tx, claims.UserID, now)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{
+		Message:       "Two-factor authentication enabled",
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableMFAHandler は2要素認証の無効化ハンドラーです（認証が必要）
+// パスワードとTOTPコード（またはリカバリーコード）の両方が必要です
+func DisableMFAHandler(c *gin.Context) {
+	user, ok := verifyMFAPassword(c)
+	if !ok {
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.TOTPCredential{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(user).Update("mfa_enabled", false).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodesHandler はリカバリーコードの再発行ハンドラーです（認証が必要）
+// 以前のリカバリーコードは全て使用できなくなります
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+	user, ok := verifyMFAPassword(c)
+	if !ok {
+		return
+	}
+
+	var codes []string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = generateRecoveryCodes(tx, user.ID, clock())
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{
+		Message:       "Recovery codes regenerated",
+		RecoveryCodes: codes,
+	})
+}
+
+// verifyMFAPassword はパスワードと2要素目を検証し、2要素認証が有効なユーザーを返します
+// 失敗した場合はレスポンスを書き込んでfalseを返します
+func verifyMFAPassword(c *gin.Context) (*models.User, bool) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return nil, false
+	}
+
+	var req models.MFAPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	var user models.User
+	if err := database.GetDB().Where("user_id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if !user.MFAEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return nil, false
+	}
+	if !CheckPasswordHash(req.Password, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return nil, false
+	}
+
+	var valid bool
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		valid, err = verifySecondFactor(tx, user.ID, req.Code, clock())
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return nil, false
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// createMFAChallenge はパスワード認証に成功したユーザーのMFAトークンを発行します
+func createMFAChallenge(userID uint, now time.Time) (string, error) {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 期限切れのトークンは新しいログインの際に削除する
+		if err := tx.Where("user_id = ? AND expires_at < ?", userID, now).Delete(&models.MFAChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.MFAChallenge{
+			UserID:    userID,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(mfaChallengeTTL),
+			CreatedAt: now,
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// MFALoginHandler は2要素認証ログインハンドラーです
+// LoginHandlerが返したMFAトークンとTOTPコード（またはリカバリーコード）を受け取り、トークンを発行します
+func MFALoginHandler(c *gin.Context) {
+	var req models.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := clock()
+	var (
+		user  models.User
+		valid bool
+	)
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var challenge models.MFAChallenge
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hashToken(req.MFAToken)).First(&challenge).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidMFAChallenge
+		}
+		if err != nil {
+			return err
+		}
+		if challenge.UsedAt != nil || !now.Before(challenge.ExpiresAt) || challenge.Attempts >= maxMFAAttempts {
+			return errInvalidMFAChallenge
+		}
+		if err := tx.Where("user_id = ?", challenge.UserID).First(&user).Error; err != nil {
+			return err
+		}
+
+		valid, err = verifySecondFactor(tx, challenge.UserID, req.Code, now)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			// 失敗回数を記録してコミットする（上限に達したトークンは使用できなくなる）
+			return tx.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+		return tx.Model(&challenge).Update("used_at", now).Error
+	})
+	if errors.Is(err, errInvalidMFAChallenge) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+
+	if !valid {
+		if err := loginLimiter.RecordFailure(user.Username, c.ClientIP(), now); err != nil {
+			log.Printf("Warning: Failed to record login failure: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := loginLimiter.RecordSuccess(user.Username); err != nil {
+		log.Printf("Warning: Failed to reset login attempts: %v", err)
+	}
+
+	// last_loginを更新
+	user.LastLogin = &now
+	database.GetDB().Model(&user).Update("last_login", now)
+
+	response, err := newAuthResponse(c, "Login successful", &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondMFARequired はMFAトークンを発行し、2要素目の入力を求めるレスポンスを返します
+func respondMFARequired(c *gin.Context, user *models.User, now time.Time) {
+	token, err := createMFAChallenge(user.ID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MFARequiredResponse{
+		Message:     "Two-factor authentication required",
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(mfaChallengeTTL.Seconds()),
+	})
+}
+
+// verifySecondFactor はTOTPコードまたはリカバリーコードを検証します
+// 6桁の数字はTOTPコード、それ以外はリカバリーコードとして扱い、どちらも1回限り有効です
+func verifySecondFactor(tx *gorm.DB, userID uint, code string, now time.Time) (bool, error) {
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		var credential models.TOTPCredential
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND confirmed_at IS NOT NULL", userID).
+			First(&credential).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		step, valid, err := validateTOTPCode(&credential, code, now)
+		if err != nil || !valid {
+			return false, err
+		}
+
+		updates := map[string]interface{}{"last_used_step": step}
+		// 暗号化の鍵を設定する前に保存した秘密鍵は、使用時に暗号化し直す
+		if totpSecretCipher != nil && !isTOTPSecretEncrypted(credential.Secret) {
+			sealed, err := sealTOTPSecret(credential.Secret, userID)
+			if err != nil {
+				return false, err
+			}
+			updates["secret"] = sealed
+		}
+		return true, tx.Model(&credential).Updates(updates).Error
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// validateTOTPCode はTOTPコードを検証し、一致したタイムステップを返します
+// 最後に使用したステップ以前のコードは再利用とみなして拒否します
+func validateTOTPCode(credential *models.TOTPCredential, code string, now time.Time) (int64, bool, error) {
+	secret, err := openTOTPSecret(credential.Secret, credential.UserID)
+	if err != nil {
+		return 0, false, err
+	}
+	step, valid := totp.Validate(secret, code, now, totpSkew)
+	if !valid || step <= credential.LastUsedStep {
+		return 0, false, nil
+	}
+	return step, true, nil
+}
+
+// isTOTPCode はコードがTOTPコードの形式（6桁の数字）かを返します
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRecoveryCodes は以前のリカバリーコードを削除して新しいコードを発行します
+func generateRecoveryCodes(tx *gorm.DB, userID uint, now time.Time) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := recoveryCodeEncoding.EncodeToString(b) // 8文字
+		codes = append(codes, code[:4]+"-"+code[4:])
+		records = append(records, models.RecoveryCode{
+			UserID:    userID,
+			CodeHash:  hashToken(code),
+			CreatedAt: now,
+		})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode は区切りや大文字小文字の違いを取り除きます
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// initMFA は2要素認証の設定を反映します
+func initMFA(issuer string, challengeTTL time.Duration, secretKey string) error {
+	if issuer != "" {
+		mfaIssuer = issuer
+	}
+	if challengeTTL > 0 {
+		mfaChallengeTTL = challengeTTL
+	}
+	return initTOTPSecretKey(secretKey)
+}
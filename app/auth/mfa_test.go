@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/totp"
+
+	"gorm.io/gorm"
+)
+
+// mfaNow はテストで使う固定時刻です
+var mfaNow = time.Unix(1700000000, 0)
+
+// setClock はclockを固定時刻にし、テスト終了時に元に戻します
+func setClock(t *testing.T, now time.Time) {
+	t.Helper()
+	original := clock
+	t.Cleanup(func() { clock = original })
+	clock = func() time.Time { return now }
+}
+
+func TestValidateTOTPCodeReplay(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := totp.Step(mfaNow)
+	code := func(offset int) string {
+		c, err := totp.Code(secret, mfaNow.Add(time.Duration(offset)*totp.Period))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name         string
+		lastUsedStep int64
+		code         string
+		wantStep     int64
+		wantOK       bool
+	}{
+		{"never used", 0, code(0), current, true},
+		{"after the previous step", current - 1, code(0), current, true},
+		{"same step is a replay", current, code(0), 0, false},
+		{"earlier step within skew after a later step", current, code(-1), 0, false},
+		{"later step within skew", current, code(1), current + 1, true},
+		{"outside skew", 0, code(-2), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credential := &models.TOTPCredential{Secret: secret, LastUsedStep: tt.lastUsedStep}
+			step, ok, err := validateTOTPCode(credential, tt.code, mfaNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if step != tt.wantStep || ok != tt.wantOK {
+				t.Errorf("validateTOTPCode() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	for _, code := range []string{"abcd-efgh", "ABCD-EFGH", "abcd efgh", " abcdefgh"} {
+		if got := normalizeRecoveryCode(code); got != "abcdefgh" {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", code, got, "abcdefgh")
+		}
+	}
+}
+
+// openTestDB はTEST_DB_NAMEのPostgreSQLに接続します（未設定の場合はテストをスキップ）
+// 接続先はDB_HOST・DB_PORT・DB_USER・DB_PASSWORDで指定します
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+	port := os.Getenv("DB_PORT")
+	if port == "" {
+		port = "5432"
+	}
+	host := os.Getenv("DB_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	err := database.Connect(config.DatabaseConfig{
+		Host:     host,
+		Port:     port,
+		User:     os.Getenv("DB_USER"),
+		Password: os.Getenv("DB_PASSWORD"),
+		Name:     name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database.GetDB()
+}
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	db := openTestDB(t)
+	setClock(t, mfaNow)
+
+	user := models.User{
+		Username:     "recovery-test-" + time.Now().Format("150405.000000"),
+		Email:        "recovery-test-" + time.Now().Format("150405.000000") + "@example.com",
+		PasswordHash: "x",
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Delete(&user) })
+
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = generateRecoveryCodes(tx, user.ID, clock())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("len(codes) = %d, want %d", len(codes), recoveryCodeCount)
+	}
+
+	verify := func(code string) bool {
+		t.Helper()
+		valid, err := verifySecondFactor(db, user.ID, code, clock())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return valid
+	}
+
+	if !verify(codes[0]) {
+		t.Fatal("first use was rejected")
+	}
+	if verify(codes[0]) {
+		t.Error("second use was accepted")
+	}
+	// 大文字小文字が違っても同じコードとして扱う
+	if !verify(strings.ToUpper(codes[1])) {
+		t.Error("uppercase code was rejected")
+	}
+	if verify(codes[1]) {
+		t.Error("code was accepted after use in a different case")
+	}
+
+	var used models.RecoveryCode
+	if err := db.Where("user_id = ? AND code_hash = ?", user.ID, hashToken(normalizeRecoveryCode(codes[0]))).First(&used).Error; err != nil {
+		t.Fatal(err)
+	}
+	if used.UsedAt == nil || !used.UsedAt.Equal(mfaNow) {
+		t.Errorf("UsedAt = %v, want %v", used.UsedAt, mfaNow)
+	}
+
+	// 再発行すると以前のコードは使えなくなる
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := generateRecoveryCodes(tx, user.ID, clock())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verify(codes[2]) {
+		t.Error("code was accepted after regeneration")
+	}
+}
@@ -1,16 +1,36 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// authRealm はWWW-Authenticateヘッダーのrealmです
+const authRealm = "tango"
+
+// tokenErrorMessages は検証エラーの理由ごとのレスポンスのメッセージです
+var tokenErrorMessages = map[error]string{
+	ErrTokenMalformed:        "Token is malformed",
+	ErrTokenInvalidSignature: "Token signature is invalid",
+	ErrTokenExpired:          "Token has expired",
+	ErrTokenNotYetValid:      "Token is not valid yet",
+	ErrTokenInvalidAudience:  "Token audience is invalid",
+	ErrTokenInvalidIssuer:    "Token issuer is invalid",
+	ErrTokenInvalidClaims:    "Invalid token",
+}
+
 // Middleware はJWT認証ミドルウェアを返します
+// 認証に失敗した場合はRFC 6750のWWW-Authenticateヘッダーで理由を返します
 func Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
+			c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", authRealm))
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
 			return
@@ -21,15 +41,107 @@ func Middleware() gin.HandlerFunc {
 			tokenString = tokenString[7:]
 		}
 
+		// 個人APIキーはルートに応じたスコープを確認する
+		if isAPIKey(tokenString) {
+			authenticateWithAPIKey(c, tokenString)
+			return
+		}
+
 		claims, err := ValidateJWT(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			message, ok := tokenErrorMessages[err]
+			if !ok {
+				message = tokenErrorMessages[ErrTokenInvalidClaims]
+			}
+			abortInvalidToken(c, message)
+			return
+		}
+
+		// ログアウトや全端末ログアウトで失効したトークンを拒否
+		revoked, err := isTokenRevoked(claims, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
 			c.Abort()
 			return
 		}
+		if revoked {
+			abortInvalidToken(c, "Token has been revoked")
+			return
+		}
 
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
+
+// authenticateWithAPIKey はAPIキーで認証し、ルートに必要なスコープを持つ場合のみ処理を続けます
+func authenticateWithAPIKey(c *gin.Context, key string) {
+	claims, scopes, err := authenticateAPIKey(key, time.Now())
+	if errors.Is(err, errAPIKeyExpired) {
+		abortInvalidToken(c, "API key has expired")
+		return
+	}
+	if errors.Is(err, errInvalidAPIKey) {
+		abortInvalidToken(c, "Invalid API key")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+		c.Abort()
+		return
+	}
+
+	scope, ok := requiredScope(c)
+	if !ok {
+		c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"insufficient_scope\"", authRealm))
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot be used for this endpoint"})
+		c.Abort()
+		return
+	}
+	if !hasScope(scopes, scope) {
+		c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"insufficient_scope\", scope=%q", authRealm, scope))
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key does not have the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("claims", claims)
+	c.Set("api_key_scopes", scopes)
+	c.Next()
+}
+
+// abortInvalidToken はinvalid_tokenのWWW-Authenticateヘッダーを付けて401を返します
+func abortInvalidToken(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\", error_description=%q", authRealm, message))
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
+
+// isTokenRevoked はトークンのjtiやセッションが失効しているか、トークンバージョンが古いかを確認します
+// セッションが有効な場合は最終アクセス日時も更新されます
+func isTokenRevoked(claims *Claims, now time.Time) (bool, error) {
+	revoked, err := revocations.IsRevoked(claims.ID, now)
+	if err != nil || revoked {
+		return revoked, err
+	}
+	version, err := revocations.TokenVersion(claims.UserID, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 削除されたユーザーのトークン
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if claims.TokenVersion != version {
+		return true, nil
+	}
+	if claims.SessionID == 0 {
+		return false, nil
+	}
+	active, err := sessions.Check(claims.SessionID, now)
+	return !active, err
+}
@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/database"
+	"backend/mail"
+	"backend/models"
+	"backend/oidc"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// oidcRequestTimeout はプロバイダーへのリクエスト（設定情報・トークン・JWKS）のタイムアウトです
+const oidcRequestTimeout = 15 * time.Second
+
+var (
+	oidcProviders = map[string]*oidc.Provider{}
+	oidcStateTTL  = 10 * time.Minute
+)
+
+var (
+	errInvalidOIDCState    = errors.New("invalid or expired oidc state")
+	errOIDCEmailUnverified = errors.New("identity provider did not return a verified email")
+	errOIDCLinkUnverified  = errors.New("existing account email is not verified")
+)
+
+// InitOIDC は設定されたOpenID Connectプロバイダーを登録します
+func InitOIDC(providers []config.OIDCProviderConfig, stateTTL time.Duration) {
+	oidcProviders = make(map[string]*oidc.Provider, len(providers))
+	for _, cfg := range providers {
+		oidcProviders[cfg.Name] = oidc.NewProvider(cfg, nil)
+	}
+	if stateTTL > 0 {
+		oidcStateTTL = stateTTL
+	}
+}
+
+// OIDCStartHandler は外部プロバイダーでのログイン開始ハンドラーです
+// state・nonce・PKCEのcode_verifierを保存し、ユーザーを遷移させる認可URLを返します
+func OIDCStartHandler(c *gin.Context) {
+	provider, ok := oidcProvider(c)
+	if !ok {
+		return
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), oidcRequestTimeout)
+	defer cancel()
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("Warning: OIDC provider %s is unavailable: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	now := time.Now()
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 期限切れのstateは新しいログインの際に削除する
+		if err := tx.Where("expires_at < ?", now).Delete(&models.OIDCLoginState{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OIDCLoginState{
+			Provider:     provider.Name(),
+			StateHash:    hashToken(state),
+			Nonce:        nonce,
+			CodeVerifier: verifier,
+			ExpiresAt:    now.Add(oidcStateTTL),
+			CreatedAt:    now,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.OIDCStartResponse{
+		AuthorizationURL: authURL,
+		State:            state,
+		ExpiresIn:        int64(oidcStateTTL.Seconds()),
+	})
+}
+
+// OIDCCallbackHandler は外部プロバイダーからのコールバックハンドラーです
+// 認可コードをIDトークンと交換して検証し、紐付いたユーザー（なければ確認済みメールアドレスで
+// 既存のユーザーに紐付けるか新しく作成したユーザー）のトークンを発行します
+func OIDCCallbackHandler(c *gin.Context) {
+	provider, ok := oidcProvider(c)
+	if !ok {
+		return
+	}
+
+	var req models.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// stateは認可コードの交換に失敗しても再利用できないよう、先に使用済みにする
+	now := clock()
+	var state models.OIDCLoginState
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ? AND provider = ?", hashToken(req.State), provider.Name()).
+			First(&state).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidOIDCState
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&state).Error
+	})
+	if err == nil && !now.Before(state.ExpiresAt) {
+		err = errInvalidOIDCState
+	}
+	if errors.Is(err, errInvalidOIDCState) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify login state"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), oidcRequestTimeout)
+	defer cancel()
+	identity, err := provider.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if errors.Is(err, oidc.ErrTokenRejected) || errors.Is(err, oidc.ErrInvalidIDToken) {
+		log.Printf("Warning: OIDC login with %s failed: %v", provider.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider login failed"})
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: OIDC provider %s is unavailable: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	user, created, linked, err := resolveOIDCUser(provider.Name(), identity, now)
+	switch {
+	case errors.Is(err, errOIDCEmailUnverified):
+		c.JSON(http.StatusForbidden, gin.H{"error": "The identity provider did not return a verified email address"})
+		return
+	case errors.Is(err, errOIDCLinkUnverified):
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists. Log in with your password and verify your email to link it"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	if linked {
+		sendMailAsync(mail.Message{
+			To:      user.Email,
+			Subject: "New sign-in method linked",
+			Body: fmt.Sprintf("Hello %s,\n\nYour %s account was linked to your account and can now be used to log in.\n\nIf you did not do this, please reset your password and contact support.\n",
+				user.Username, provider.Name()),
+		})
+	}
+
+	// 2要素認証が有効な場合は外部プロバイダーでのログインでも2要素目を求める
+	if user.MFAEnabled {
+		respondMFARequired(c, user, now)
+		return
+	}
+
+	// last_loginを更新
+	user.LastLogin = &now
+	database.GetDB().Model(user).Update("last_login", now)
+
+	message, status := "Login successful", http.StatusOK
+	if created {
+		message, status = "User created successfully", http.StatusCreated
+	}
+	response, err := newAuthResponse(c, message, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(status, response)
+}
+
+// oidcProvider はURLパラメータのプロバイダーを返します
+// 設定されていない場合は404を返してfalseを返します
+func oidcProvider(c *gin.Context) (*oidc.Provider, bool) {
+	provider, ok := oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+		return nil, false
+	}
+	return provider, true
+}
+
+// resolveOIDCUser はIDトークンのユーザーを探し、なければ紐付けるか作成します
+func resolveOIDCUser(provider string, claims *oidc.Claims, now time.Time) (user *models.User, created, linked bool, err error) {
+	user = &models.User{}
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var identity models.OIDCIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+		if err == nil {
+			if err := tx.Where("user_id = ?", identity.UserID).First(user).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]interface{}{"email": claims.Email, "last_login_at": now}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		email, err := verifiedOIDCEmail(claims)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("LOWER(email) = LOWER(?)", email).First(user).Error
+		switch {
+		case err == nil:
+			if err := checkOIDCLink(claims, user); err != nil {
+				return err
+			}
+			linked = true
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := createVerifiedUser(tx, user, email, now); err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+
+		return tx.Create(&models.OIDCIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: &now,
+			CreatedAt:   now,
+		}).Error
+	})
+	if err != nil {
+		return nil, false, false, err
+	}
+	return user, created, linked, nil
+}
+
+// verifiedOIDCEmail はIDトークンの確認済みのメールアドレスを返します
+func verifiedOIDCEmail(claims *oidc.Claims) (string, error) {
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !bool(claims.EmailVerified) {
+		return "", errOIDCEmailUnverified
+	}
+	return email, nil
+}
+
+// checkOIDCLink は同じメールアドレスの既存のユーザーにIDトークンのアカウントを紐付けられるかを確認します
+// プロバイダーとこのアプリの両方でメールアドレスが確認済みの場合に限ります
+// （未確認のアカウントを他人が先に登録しておき、乗っ取る攻撃を防ぐ）
+func checkOIDCLink(claims *oidc.Claims, user *models.User) error {
+	if _, err := verifiedOIDCEmail(claims); err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return errOIDCLinkUnverified
+	}
+	return nil
+}
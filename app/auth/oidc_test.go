@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/oidc"
+	"backend/oidc/oidctest"
+)
+
+func TestCheckOIDCLink(t *testing.T) {
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name            string
+		claims          oidc.Claims
+		localVerifiedAt *time.Time
+		wantErr         error
+	}{
+		{"verified on both sides", oidc.Claims{Email: "user@example.com", EmailVerified: true}, &verifiedAt, nil},
+		{"unverified at provider", oidc.Claims{Email: "user@example.com", EmailVerified: false}, &verifiedAt, errOIDCEmailUnverified},
+		{"unverified locally", oidc.Claims{Email: "user@example.com", EmailVerified: true}, nil, errOIDCLinkUnverified},
+		{"unverified on both sides", oidc.Claims{Email: "user@example.com", EmailVerified: false}, nil, errOIDCEmailUnverified},
+		{"missing email", oidc.Claims{EmailVerified: true}, &verifiedAt, errOIDCEmailUnverified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &models.User{Email: "user@example.com", EmailVerifiedAt: tt.localVerifiedAt}
+			if err := checkOIDCLink(&tt.claims, user); err != tt.wantErr {
+				t.Errorf("checkOIDCLink() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// exchangeTestLogin はoidctestのIssuerでuserとしてログインし、検証したIDトークンのクレームを返します
+func exchangeTestLogin(t *testing.T, user oidctest.User) *oidc.Claims {
+	t.Helper()
+	issuer, err := oidctest.NewIssuer("test-client", "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(issuer.Close)
+	issuer.SetUser(user)
+	provider := oidc.NewProvider(issuer.Config("test", "http://localhost/callback"), nil)
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := issuer.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := provider.Exchange(context.Background(), code, verifier, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestResolveOIDCUserLinking(t *testing.T) {
+	db := openTestDB(t)
+	verifiedAt := mfaNow
+
+	tests := []struct {
+		name            string
+		idpVerified     bool
+		localVerifiedAt *time.Time
+		wantErr         error
+	}{
+		{"verified on both sides", true, &verifiedAt, nil},
+		{"unverified at provider", false, &verifiedAt, errOIDCEmailUnverified},
+		{"unverified locally", true, nil, errOIDCLinkUnverified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suffix := time.Now().Format("150405.000000")
+			local := models.User{
+				Username:        "oidc-link-" + suffix,
+				Email:           "oidc-link-" + suffix + "@example.com",
+				PasswordHash:    "x",
+				EmailVerifiedAt: tt.localVerifiedAt,
+			}
+			if err := db.Create(&local).Error; err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				db.Where("user_id = ?", local.ID).Delete(&models.OIDCIdentity{})
+				db.Delete(&local)
+			})
+
+			claims := exchangeTestLogin(t, oidctest.User{
+				Subject:       "subject-" + suffix,
+				Email:         local.Email,
+				EmailVerified: tt.idpVerified,
+			})
+			user, created, linked, err := resolveOIDCUser("test", claims, mfaNow)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveOIDCUser() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var count int64
+			db.Model(&models.OIDCIdentity{}).Where("user_id = ?", local.ID).Count(&count)
+			if tt.wantErr != nil {
+				if count != 0 {
+					t.Errorf("identities = %d, want 0", count)
+				}
+				return
+			}
+			if created || !linked || user.ID != local.ID || count != 1 {
+				t.Errorf("resolveOIDCUser() = user %d, created %v, linked %v, identities %d, want user %d linked",
+					user.ID, created, linked, count, local.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/database"
+	"backend/mail"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errInvalidResetToken は存在しない・使用済み・期限切れのリセットトークンのエラーです
+var errInvalidResetToken = errors.New("invalid or expired reset token")
+
+// ForgotPasswordHandler はパスワードリセット申請ハンドラーです
+// メールアドレスが登録されているかどうかに関わらず同じレスポンスを返します
+func ForgotPasswordHandler(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := requestPasswordReset(req.Email, time.Now()); err != nil {
+		// エラーの有無もレスポンスに含めない
+		log.Printf("Warning: Failed to create password reset token: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If the email address is registered, a password reset link has been sent",
+	})
+}
+
+// requestPasswordReset はリセットトークンを発行してメールを送信します
+// ユーザーが存在しない場合は何もしません
+func requestPasswordReset(email string, now time.Time) error {
+	var user models.User
+	err := database.GetDB().Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	record := models.PasswordResetToken{
+		UserID:    user.ID,
+		ExpiresAt: now.Add(passwordResetTTL),
+		CreatedAt: now,
+	}
+	var token string
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		token, err = issueSingleUseToken(tx, &record, &record.TokenHash, "user_id = ?", user.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	sendMailAsync(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nWe received a request to reset your password. Open the link below to choose a new password:\n\n%s\n\nThis link expires in %s and can be used only once. If you did not request a password reset, you can ignore this email.\n",
+			user.Username, appLink("/reset-password", token), passwordResetTTL),
+	})
+	return nil
+}
+
+// ResetPasswordHandler はパスワードリセットハンドラーです
+// リセット後は全ての端末のトークンとセッション、APIキーを失効させます
+func ResetPasswordHandler(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// パスワードのハッシュ化は重いため、無効なトークンのリクエストはハッシュ化する前に拒否する
+	// （トランザクション内でロックを取得してからもう一度確認する）
+	now := time.Now()
+	err := checkResetToken(database.GetDB(), req.Token, now)
+	if errors.Is(err, errInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	hashedPassword, err := HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var userID uint
+	var version int
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hashToken(req.Token)).First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		if token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+			return errInvalidResetToken
+		}
+
+		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		userID = token.UserID
+		if err := tx.Model(&models.User{}).Where("user_id = ?", token.UserID).Update("password_hash", hashedPassword).Error; err != nil {
+			return err
+		}
+
+		// 以前のパスワードで発行されたトークンは全て無効にする
+		version, err = revokeAllTx(tx, token.UserID, 0, now)
+		return err
+	})
+	if errors.Is(err, errInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	revocations.CacheTokenVersion(userID, version, now)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password reset successfully",
+	})
+}
+
+// checkResetToken はリセットトークンが未使用で期限内かをロックせずに確認します
+func checkResetToken(db *gorm.DB, token string, now time.Time) error {
+	var count int64
+	err := db.Model(&models.PasswordResetToken{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), now).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errInvalidResetToken
+	}
+	return nil
+}
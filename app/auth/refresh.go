@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errInvalidRefreshToken = errors.New("invalid refresh token")
+	errRefreshTokenExpired = errors.New("refresh token expired")
+	errRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// generateOpaqueToken はURLセーフな推測不可能なトークンを生成します
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken はデータベースに保存するトークンのハッシュ（SHA-256）を返します
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken はリフレッシュトークンを発行して保存します
+// familyIDが空の場合は新しいトークンファミリー（新しい端末のログイン）を作成します
+func issueRefreshToken(tx *gorm.DB, userID uint, familyID string, now time.Time) (*models.RefreshToken, string, error) {
+	if familyID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, "", err
+		}
+		familyID = hex.EncodeToString(id)
+
+		// 新しいログインの際に期限切れのトークンを削除する
+		if err := tx.Where("user_id = ? AND expires_at < ?", userID, now).Delete(&models.RefreshToken{}).Error; err != nil {
+			return nil, "", err
+		}
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	record := &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(refreshTokenTTL),
+		CreatedAt: now,
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return nil, "", err
+	}
+	return record, token, nil
+}
+
+// newAuthResponse は新しいセッションを作成し、アクセストークンとリフレッシュトークンを発行してレスポンスを作成します
+func newAuthResponse(c *gin.Context, message string, user *models.User) (*models.AuthResponse, error) {
+	now := time.Now()
+	var token, refreshToken string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		record, plain, err := issueRefreshToken(tx, user.ID, "", now)
+		if err != nil {
+			return err
+		}
+		session, err := createSession(tx, c, user.ID, record.FamilyID, record.ExpiresAt, now)
+		if err != nil {
+			return err
+		}
+		refreshToken = plain
+		token, err = GenerateJWT(user, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		Message:      message,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+		User:         user.ToResponse(),
+	}, nil
+}
+
+// rotateRefreshToken はリフレッシュトークンを使用済みにして、同じファミリーの新しいトークンとアクセストークンを発行します
+// 使用済みのトークンが再利用された場合は、盗用とみなしてファミリー全体を失効させます
+func rotateRefreshToken(c *gin.Context, token string, now time.Time) (*models.AuthResponse, error) {
+	var (
+		response *models.AuthResponse
+		reused   bool
+	)
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hashToken(token)).First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if current.RevokedAt != nil {
+			return errInvalidRefreshToken
+		}
+		if current.UsedAt != nil {
+			// 失効はコミットする必要があるためエラーにはしない
+			reused = true
+			return revokeTokenFamily(tx, current.FamilyID, now)
+		}
+		if !now.Before(current.ExpiresAt) {
+			return errRefreshTokenExpired
+		}
+
+		var user models.User
+		if err := tx.Where("user_id = ?", current.UserID).First(&user).Error; err != nil {
+			return err
+		}
+
+		var session models.Session
+		err = tx.Where("family_id = ?", current.FamilyID).First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// セッション導入前に発行されたトークンはここでセッションを作成する
+			created, err := createSession(tx, c, user.ID, current.FamilyID, current.ExpiresAt, now)
+			if err != nil {
+				return err
+			}
+			session = *created
+		} else if err != nil {
+			return err
+		}
+		if session.RevokedAt != nil {
+			return errInvalidRefreshToken
+		}
+
+		next, refreshToken, err := issueRefreshToken(tx, user.ID, current.FamilyID, now)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&current).Updates(map[string]interface{}{
+			"used_at":        now,
+			"replaced_by_id": next.ID,
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&session).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"expires_at":   next.ExpiresAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		accessToken, err := GenerateJWT(&user, session.ID)
+		if err != nil {
+			return err
+		}
+		response = &models.AuthResponse{
+			Message:      "Token refreshed successfully",
+			Token:        accessToken,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int64(accessTokenTTL.Seconds()),
+			User:         user.ToResponse(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, errRefreshTokenReused
+	}
+	return response, nil
+}
+
+// revokeTokenFamily はトークンファミリーの未失効のトークンと、対応するセッションを失効させます
+func revokeTokenFamily(tx *gorm.DB, familyID string, now time.Time) error {
+	err := tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// RefreshHandler はリフレッシュトークンをローテーションしてアクセストークンを再発行するハンドラーです
+func RefreshHandler(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := rotateRefreshToken(c, req.RefreshToken, time.Now())
+	switch {
+	case errors.Is(err, errRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected. Please log in again"})
+		return
+	case errors.Is(err, errRefreshTokenExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
+		return
+	case errors.Is(err, errInvalidRefreshToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// revocations はミドルウェアが参照する失効ストアです
+var revocations = newRevocationStore(30 * time.Second)
+
+// revocationEntry はキャッシュしたjtiの失効状態です
+type revocationEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+// versionEntry はキャッシュしたユーザーのトークンバージョンです
+type versionEntry struct {
+	version   int
+	expiresAt time.Time
+}
+
+// revocationStore はアクセストークンの失効状態を管理します
+// データベースを正とし、問い合わせ結果をメモリにキャッシュします。
+// 失効済みのjtiはトークンの有効期限まで、それ以外はttlの間キャッシュするため、
+// 他のサーバーで行われた失効が反映されるまで最大でttlかかります
+type revocationStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	tokens    map[string]revocationEntry
+	versions  map[uint]versionEntry
+	lastSweep time.Time
+}
+
+// newRevocationStore は失効ストアを作成します
+func newRevocationStore(ttl time.Duration) *revocationStore {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &revocationStore{
+		ttl:      ttl,
+		tokens:   make(map[string]revocationEntry),
+		versions: make(map[uint]versionEntry),
+	}
+}
+
+// IsRevoked はjtiが失効しているかを返します
+func (s *revocationStore) IsRevoked(jti string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	entry, ok := s.tokens[jti]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	var count int64
+	if err := database.GetDB().Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[jti] = revocationEntry{revoked: count > 0, expiresAt: now.Add(s.ttl)}
+	s.sweep(now)
+	return count > 0, nil
+}
+
+// Revoke はjtiを失効させます。expiresAtにはトークンの有効期限を指定します
+func (s *revocationStore) Revoke(jti string, userID uint, expiresAt, now time.Time) error {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 有効期限を過ぎた行は不要なため、失効の記録時に削除する
+		if err := tx.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+			JTI:       jti,
+			UserID:    userID,
+			ExpiresAt: expiresAt,
+			RevokedAt: now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[jti] = revocationEntry{revoked: true, expiresAt: expiresAt}
+	s.sweep(now)
+	return nil
+}
+
+// TokenVersion はユーザーの現在のトークンバージョンを返します
+func (s *revocationStore) TokenVersion(userID uint, now time.Time) (int, error) {
+	s.mu.Lock()
+	entry, ok := s.versions[userID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.version, nil
+	}
+
+	var user models.User
+	if err := database.GetDB().Select("user_id", "token_version").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.versions[userID] = versionEntry{version: user.TokenVersion, expiresAt: now.Add(s.ttl)}
+	s.sweep(now)
+	return user.TokenVersion, nil
+}
+
+// RevokeAll はユーザーのトークンバージョンを上げて発行済みの全てのアクセストークンを無効にし、
+// keepSessionID以外の全てのリフレッシュトークンとセッション（0の場合は全て）と、全てのAPIキーを失効させます
+// 新しいトークンバージョンを返します
+func (s *revocationStore) RevokeAll(userID, keepSessionID uint, now time.Time) (int, error) {
+	var version int
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		version, err = revokeAllTx(tx, userID, keepSessionID, now)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.CacheTokenVersion(userID, version, now)
+	return version, nil
+}
+
+// RevokeAllTokensTx はユーザーの全てのアクセストークン・リフレッシュトークン・セッション・APIキーを
+// トランザクション内で失効させ、新しいトークンバージョンを返します
+// 役割の変更など、トークンのクレームを無効にする必要がある場合に使います。コミット後にCacheTokenVersionを呼び出してください
+func RevokeAllTokensTx(tx *gorm.DB, userID uint, now time.Time) (int, error) {
+	return revokeAllTx(tx, userID, 0, now)
+}
+
+// CacheTokenVersion はRevokeAllTokensTxでコミットしたトークンバージョンをキャッシュします
+func CacheTokenVersion(userID uint, version int, now time.Time) {
+	revocations.CacheTokenVersion(userID, version, now)
+}
+
+// CacheTokenVersion はコミットしたトークンバージョンをキャッシュします
+// revokeAllTxを呼び出したトランザクションのコミット後に呼び出します
+func (s *revocationStore) CacheTokenVersion(userID uint, version int, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.versions[userID] = versionEntry{version: version, expiresAt: now.Add(s.ttl)}
+}
+
+// revokeAllTx はRevokeAllと同じ失効をトランザクション内で行い、新しいトークンバージョンを返します
+// パスワードの更新などと同じトランザクションで失効させる場合に使います
+func revokeAllTx(tx *gorm.DB, userID, keepSessionID uint, now time.Time) (int, error) {
+	var version int
+	err := tx.Raw("UPDATE users SET token_version = token_version + 1 WHERE user_id = ? RETURNING token_version", userID).
+		Scan(&version).Error
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Where("family_id NOT IN (?)", tx.Model(&models.Session{}).Select("family_id").Where("id = ?", keepSessionID)).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// sweep は期限切れのキャッシュを削除します（ttlごとに1回、ロック取得中に呼び出す）
+func (s *revocationStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for jti, entry := range s.tokens {
+		if !now.Before(entry.expiresAt) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, entry := range s.versions {
+		if !now.Before(entry.expiresAt) {
+			delete(s.versions, userID)
+		}
+	}
+}
+
+// LogoutHandler はログアウトハンドラーです（認証が必要）
+// 使用中のアクセストークンとセッション（端末のリフレッシュトークン）を失効させます。
+// セッションを持たない古いトークンの場合は、refresh_tokenで指定したリフレッシュトークンを失効させます。
+// all_devicesを指定した場合は全ての端末のアクセストークンとリフレッシュトークン、APIキーを失効させます
+func LogoutHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var req models.LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	if err := revocations.Revoke(claims.ID, claims.UserID, claims.ExpiresAt.Time, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	var err error
+	switch {
+	case req.AllDevices:
+		_, err = revocations.RevokeAll(claims.UserID, 0, now)
+	case claims.SessionID != 0:
+		var session models.Session
+		err = database.GetDB().Where("id = ? AND user_id = ?", claims.SessionID, claims.UserID).First(&session).Error
+		if err == nil {
+			err = revokeSession(&session, now)
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+	case req.RefreshToken != "":
+		var token models.RefreshToken
+		err = database.GetDB().Where("token_hash = ? AND user_id = ?", hashToken(req.RefreshToken), claims.UserID).First(&token).Error
+		if err == nil {
+			err = revokeTokenFamily(database.GetDB(), token.FamilyID, now)
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 他のユーザーのトークンや不明なトークンは無視する
+			err = nil
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	message := "Logged out successfully"
+	if req.AllDevices {
+		message = "Logged out from all devices successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+	})
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// roleRanks は役割の権限の強さの順序です
+var roleRanks = map[string]int{
+	models.RoleUser:   0,
+	models.RoleEditor: 1,
+	models.RoleAdmin:  2,
+}
+
+// RequireRole はminRole以上の役割を持つユーザーのみ許可するミドルウェアを返します（auth.Middlewareの後に使用）
+// 役割はアクセストークンのクレームから判定するため、変更時はトークンを失効させます
+func RequireRole(minRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := currentClaims(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+		if roleRanks[claims.Role] < roleRanks[minRole] {
+			c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"insufficient_scope\"", authRealm))
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SeedAdmin は管理者がまだいない場合に、指定したユーザーを管理者にします
+// 初回デプロイ時にADMIN_USERNAMEで最初の管理者を設定するために使用します
+func SeedAdmin(username string) error {
+	if username == "" {
+		return nil
+	}
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		result := tx.Model(&models.User{}).Where("username = ?", username).Update("role", models.RoleAdmin)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("admin user %q not found", username)
+		}
+		log.Printf("Promoted %s to admin", username)
+		return nil
+	})
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sessions はミドルウェアが参照するセッションの状態です
+var sessions = newSessionTracker(30*time.Second, 5*time.Minute)
+
+// sessionEntry はキャッシュしたセッションの状態です
+type sessionEntry struct {
+	revoked   bool
+	lastSeen  time.Time
+	expiresAt time.Time // キャッシュの有効期限
+}
+
+// sessionTracker はセッションの失効状態をキャッシュし、最終アクセス日時を間引いて更新します
+type sessionTracker struct {
+	mu            sync.Mutex
+	ttl           time.Duration
+	touchInterval time.Duration
+	entries       map[uint]sessionEntry
+	lastSweep     time.Time
+}
+
+// newSessionTracker はセッションの状態を作成します
+func newSessionTracker(ttl, touchInterval time.Duration) *sessionTracker {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	if touchInterval <= 0 {
+		touchInterval = 5 * time.Minute
+	}
+	return &sessionTracker{
+		ttl:           ttl,
+		touchInterval: touchInterval,
+		entries:       make(map[uint]sessionEntry),
+	}
+}
+
+// Check はセッションが有効かを返し、前回の更新からtouchInterval以上経過していれば最終アクセス日時を更新します
+func (t *sessionTracker) Check(sessionID uint, now time.Time) (bool, error) {
+	t.mu.Lock()
+	entry, ok := t.entries[sessionID]
+	t.mu.Unlock()
+
+	if !ok || !now.Before(entry.expiresAt) {
+		var session models.Session
+		err := database.GetDB().Select("id", "last_seen_at", "revoked_at").Where("id = ?", sessionID).First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			entry = sessionEntry{revoked: true}
+		} else if err != nil {
+			return false, err
+		} else {
+			entry = sessionEntry{revoked: session.RevokedAt != nil, lastSeen: session.LastSeenAt}
+		}
+		entry.expiresAt = now.Add(t.ttl)
+	}
+
+	if !entry.revoked && now.Sub(entry.lastSeen) >= t.touchInterval {
+		err := database.GetDB().Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", sessionID).
+			Update("last_seen_at", now).Error
+		if err != nil {
+			return false, err
+		}
+		entry.lastSeen = now
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[sessionID] = entry
+	t.sweep(now)
+	return !entry.revoked, nil
+}
+
+// Revoke はセッションを失効済みとしてキャッシュします
+func (t *sessionTracker) Revoke(sessionID uint, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[sessionID] = sessionEntry{revoked: true, expiresAt: now.Add(t.ttl)}
+}
+
+// sweep は期限切れのキャッシュを削除します（ttlごとに1回、ロック取得中に呼び出す）
+func (t *sessionTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.ttl {
+		return
+	}
+	t.lastSweep = now
+	for id, entry := range t.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(t.entries, id)
+		}
+	}
+}
+
+// createSession はログインした端末のセッションを作成します
+func createSession(tx *gorm.DB, c *gin.Context, userID uint, familyID string, expiresAt, now time.Time) (*models.Session, error) {
+	session := &models.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		UserAgent:  truncate(c.Request.UserAgent(), 255),
+		IPAddress:  c.ClientIP(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := tx.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// revokeSession はセッションとその端末のリフレッシュトークンを失効させます
+func revokeSession(session *models.Session, now time.Time) error {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return revokeTokenFamily(tx, session.FamilyID, now)
+	})
+	if err != nil {
+		return err
+	}
+	sessions.Revoke(session.ID, now)
+	return nil
+}
+
+// truncate は文字列をUTF-8として壊さずに最大バイト数に切り詰めます
+func truncate(value string, maxBytes int) string {
+	for len(value) > maxBytes {
+		_, size := utf8.DecodeLastRuneInString(value)
+		value = value[:len(value)-size]
+	}
+	return value
+}
+
+// currentClaims はミドルウェアが設定したクレームを取得します
+func currentClaims(c *gin.Context) (*Claims, bool) {
+	value, exists := c.Get("claims")
+	claims, ok := value.(*Claims)
+	if !exists || !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// ListSessionsHandler はログイン中のセッション一覧取得ハンドラーです（認証が必要）
+func ListSessionsHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	var list []models.Session
+	err := database.GetDB().
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", claims.UserID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&list).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	responses := make([]models.SessionResponse, 0, len(list))
+	for i := range list {
+		responses = append(responses, list[i].ToResponse(claims.SessionID))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": responses,
+	})
+}
+
+// RevokeSessionHandler はセッションの失効ハンドラーです（認証が必要）
+// 失効したセッションのアクセストークンとリフレッシュトークンは使用できなくなります
+func RevokeSessionHandler(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var session models.Session
+	err = database.GetDB().Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, claims.UserID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch session"})
+		return
+	}
+
+	if err := revokeSession(&session, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
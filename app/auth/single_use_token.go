@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"gorm.io/gorm"
+)
+
+// issueSingleUseToken はメールで送る1回限り有効なトークン（パスワードリセット・メールアドレス確認・ログインリンク）を発行します
+// recordのTokenHash（tokenHashに指定）にハッシュを設定して保存し、送信するトークンを返します
+// 古いリンクが使われないよう、scopeの条件に一致する未使用のトークンは削除して有効なトークンを最新の1つだけにします
+func issueSingleUseToken(tx *gorm.DB, record interface{}, tokenHash *string, scope string, args ...interface{}) (string, error) {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Where(scope, args...).Where("used_at IS NULL").Delete(record).Error; err != nil {
+		return "", err
+	}
+	*tokenHash = hashToken(token)
+	if err := tx.Create(record).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
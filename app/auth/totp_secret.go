@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// encryptedSecretPrefix は暗号化したTOTPの秘密鍵の接頭辞です（接頭辞のない値は暗号化前の秘密鍵）
+const encryptedSecretPrefix = "enc:v1:"
+
+// totpSecretCipher はTOTPの秘密鍵を暗号化するAES-GCMです（nilの場合は暗号化しない）
+var totpSecretCipher cipher.AEAD
+
+// errTOTPSecretKeyMissing は暗号化された秘密鍵を復号する鍵が設定されていない場合のエラーです
+var errTOTPSecretKeyMissing = errors.New("TOTP secret is encrypted but MFA_SECRET_KEY is not set")
+
+// initTOTPSecretKey はBase64でエンコードした32バイトの鍵からAES-256-GCMを作成します
+func initTOTPSecretKey(encodedKey string) error {
+	if encodedKey == "" {
+		log.Println("Warning: MFA_SECRET_KEY is not set. TOTP secrets are stored unencrypted.")
+		totpSecretCipher = nil
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return fmt.Errorf("invalid MFA_SECRET_KEY: %w", err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("invalid MFA_SECRET_KEY: must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	totpSecretCipher, err = cipher.NewGCM(block)
+	return err
+}
+
+// sealTOTPSecret は保存用に秘密鍵を暗号化します
+// 別のユーザーの行にコピーしても復号できないよう、ユーザーIDを追加データにします
+func sealTOTPSecret(secret string, userID uint) (string, error) {
+	if totpSecretCipher == nil {
+		return secret, nil
+	}
+	nonce := make([]byte, totpSecretCipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := totpSecretCipher.Seal(nonce, nonce, []byte(secret), secretAdditionalData(userID))
+	return encryptedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// openTOTPSecret は保存した秘密鍵を復号します（暗号化前に保存した秘密鍵はそのまま返す）
+func openTOTPSecret(stored string, userID uint) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, encryptedSecretPrefix)
+	if !ok {
+		return stored, nil
+	}
+	if totpSecretCipher == nil {
+		return "", errTOTPSecretKeyMissing
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < totpSecretCipher.NonceSize() {
+		return "", errors.New("invalid encrypted TOTP secret")
+	}
+	nonce, ciphertext := sealed[:totpSecretCipher.NonceSize()], sealed[totpSecretCipher.NonceSize():]
+	secret, err := totpSecretCipher.Open(nil, nonce, ciphertext, secretAdditionalData(userID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+// isTOTPSecretEncrypted は保存した秘密鍵が暗号化されているかを返します
+func isTOTPSecretEncrypted(stored string) bool {
+	return strings.HasPrefix(stored, encryptedSecretPrefix)
+}
+
+// secretAdditionalData はAES-GCMの追加データ（ユーザーID）です
+func secretAdditionalData(userID uint) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"backend/models"
+	"backend/totp"
+)
+
+// setTOTPSecretKey はTOTPの秘密鍵の暗号化の鍵を設定し、テスト終了時に元に戻します
+func setTOTPSecretKey(t *testing.T, key string) {
+	t.Helper()
+	original := totpSecretCipher
+	t.Cleanup(func() { totpSecretCipher = original })
+	if err := initTOTPSecretKey(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var testSecretKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+func TestSealTOTPSecret(t *testing.T) {
+	setTOTPSecretKey(t, testSecretKey)
+
+	sealed, err := sealTOTPSecret("JBSWY3DPEHPK3PXP", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isTOTPSecretEncrypted(sealed) || strings.Contains(sealed, "JBSWY3DPEHPK3PXP") {
+		t.Fatalf("sealTOTPSecret() = %q, want an encrypted value", sealed)
+	}
+	if len(sealed) > 255 {
+		t.Errorf("len(sealed) = %d, want at most the column size 255", len(sealed))
+	}
+
+	// 同じ秘密鍵でも毎回異なる値になる
+	if again, _ := sealTOTPSecret("JBSWY3DPEHPK3PXP", 1); again == sealed {
+		t.Error("sealTOTPSecret() reused a nonce")
+	}
+
+	if secret, err := openTOTPSecret(sealed, 1); err != nil || secret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("openTOTPSecret() = %q, %v, want %q", secret, err, "JBSWY3DPEHPK3PXP")
+	}
+	// 別のユーザーの行にコピーした値は復号できない
+	if _, err := openTOTPSecret(sealed, 2); err == nil {
+		t.Error("openTOTPSecret(other user) error = nil, want error")
+	}
+	raw, _ := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(sealed, encryptedSecretPrefix))
+	raw[len(raw)-1] ^= 1
+	if _, err := openTOTPSecret(encryptedSecretPrefix+base64.RawStdEncoding.EncodeToString(raw), 1); err == nil {
+		t.Error("openTOTPSecret(tampered) error = nil, want error")
+	}
+}
+
+func TestOpenTOTPSecretPlaintext(t *testing.T) {
+	setTOTPSecretKey(t, testSecretKey)
+
+	// 鍵を設定する前に保存した秘密鍵はそのまま使う
+	if secret, err := openTOTPSecret("JBSWY3DPEHPK3PXP", 1); err != nil || secret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("openTOTPSecret(plaintext) = %q, %v, want %q", secret, err, "JBSWY3DPEHPK3PXP")
+	}
+}
+
+func TestTOTPSecretWithoutKey(t *testing.T) {
+	setTOTPSecretKey(t, testSecretKey)
+	sealed, err := sealTOTPSecret("JBSWY3DPEHPK3PXP", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setTOTPSecretKey(t, "")
+	if stored, _ := sealTOTPSecret("JBSWY3DPEHPK3PXP", 1); stored != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("sealTOTPSecret() without key = %q, want plaintext", stored)
+	}
+	if _, err := openTOTPSecret(sealed, 1); err != errTOTPSecretKeyMissing {
+		t.Errorf("openTOTPSecret() error = %v, want %v", err, errTOTPSecretKeyMissing)
+	}
+}
+
+func TestInitTOTPSecretKeyInvalid(t *testing.T) {
+	setTOTPSecretKey(t, "")
+	for _, key := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("too short"))} {
+		if err := initTOTPSecretKey(key); err == nil {
+			t.Errorf("initTOTPSecretKey(%q) error = nil, want error", key)
+		}
+	}
+}
+
+func TestValidateTOTPCodeEncryptedSecret(t *testing.T) {
+	setTOTPSecretKey(t, testSecretKey)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealTOTPSecret(secret, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.Code(secret, mfaNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credential := &models.TOTPCredential{UserID: 7, Secret: sealed}
+	if step, ok, err := validateTOTPCode(credential, code, mfaNow); err != nil || !ok || step != totp.Step(mfaNow) {
+		t.Errorf("validateTOTPCode() = %d, %v, %v, want %d, true, nil", step, ok, err, totp.Step(mfaNow))
+	}
+}
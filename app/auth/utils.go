@@ -1,17 +1,232 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"backend/config"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// パスワードハッシュのアルゴリズム
+const (
+	HashAlgorithmArgon2id = "argon2id"
+	HashAlgorithmBcrypt   = "bcrypt"
+)
+
+// errUnknownHashFormat はどのアルゴリズムの形式でもないハッシュのエラーです
+var errUnknownHashFormat = errors.New("unknown password hash format")
+
+// PasswordHasher はパスワードハッシュのアルゴリズムのインターフェースです
+// ハッシュはアルゴリズムとパラメータを含む形式で保存し、後からパラメータを変更できるようにします
+type PasswordHasher interface {
+	// Hash はパスワードをハッシュ化します
+	Hash(password string) (string, error)
+	// Supports はハッシュがこのアルゴリズムの形式かを返します
+	Supports(hash string) bool
+	// Verify はパスワードを検証します
+	Verify(password, hash string) (bool, error)
+	// NeedsRehash はハッシュのパラメータが現在の設定と異なるかを返します
+	NeedsRehash(hash string) bool
+}
+
+// passwordHasher は新しいハッシュの作成に使うアルゴリズムです
+var passwordHasher PasswordHasher = NewArgon2idHasher(config.PasswordHashConfig{})
+
+// legacyHashers は以前のアルゴリズムのハッシュを検証するためのアルゴリズムです
+var legacyHashers = []PasswordHasher{
+	BcryptHasher{Cost: 14},
+	NewArgon2idHasher(config.PasswordHashConfig{}),
+}
+
 // HashPassword はパスワードをハッシュ化します
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	return passwordHasher.Hash(password)
 }
 
 // CheckPasswordHash はパスワードを検証します
 func CheckPasswordHash(password, hash string) bool {
+	ok, _ := VerifyPassword(password, hash)
+	return ok
+}
+
+// VerifyPassword はパスワードを検証し、ハッシュを現在の設定で作り直すべきかを返します
+func VerifyPassword(password, hash string) (ok bool, needsRehash bool) {
+	hasher, err := hasherFor(hash)
+	if err != nil {
+		return false, false
+	}
+	ok, err = hasher.Verify(password, hash)
+	if err != nil || !ok {
+		return false, false
+	}
+	return true, hasher != passwordHasher || passwordHasher.NeedsRehash(hash)
+}
+
+// hasherFor はハッシュの形式に対応するアルゴリズムを返します
+func hasherFor(hash string) (PasswordHasher, error) {
+	if passwordHasher.Supports(hash) {
+		return passwordHasher, nil
+	}
+	for _, hasher := range legacyHashers {
+		if hasher.Supports(hash) {
+			return hasher, nil
+		}
+	}
+	return nil, errUnknownHashFormat
+}
+
+// Argon2idHasher はargon2idでハッシュ化します
+// ハッシュはPHC形式（$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>）で保存します
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// NewArgon2idHasher は設定からargon2idのアルゴリズムを作成します（未設定の値は既定値を使用）
+func NewArgon2idHasher(cfg config.PasswordHashConfig) *Argon2idHasher {
+	h := &Argon2idHasher{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	if cfg.Argon2Memory > 0 {
+		h.Memory = cfg.Argon2Memory
+	}
+	if cfg.Argon2Iterations > 0 {
+		h.Iterations = cfg.Argon2Iterations
+	}
+	if cfg.Argon2Parallelism > 0 {
+		h.Parallelism = cfg.Argon2Parallelism
+	}
+	return h
+}
+
+// Hash はパスワードをハッシュ化します
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Supports はハッシュがargon2idの形式かを返します
+func (h *Argon2idHasher) Supports(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+// Verify はパスワードを検証します（ハッシュに含まれるパラメータを使用）
+func (h *Argon2idHasher) Verify(password, hash string) (bool, error) {
+	params, salt, key, err := parseArgon2idHash(hash)
+	if err != nil {
+		return false, err
+	}
+	actual := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+// NeedsRehash はハッシュのパラメータが現在の設定と異なるかを返します
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := parseArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.Memory || params.Iterations != h.Iterations || params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength || uint32(len(key)) != h.KeyLength
+}
+
+// parseArgon2idHash はPHC形式のargon2idハッシュを分解します
+func parseArgon2idHash(hash string) (params Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, errUnknownHashFormat
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version: %d", version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, errUnknownHashFormat
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, errUnknownHashFormat
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return params, nil, nil, errUnknownHashFormat
+	}
+	return params, salt, key, nil
+}
+
+// BcryptHasher はbcryptでハッシュ化します（以前の既定のアルゴリズム）
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash はパスワードをハッシュ化します
+func (h BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(bytes), err
+}
+
+// Supports はハッシュがbcryptの形式かを返します
+func (h BcryptHasher) Supports(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// Verify はパスワードを検証します
+func (h BcryptHasher) Verify(password, hash string) (bool, error) {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash はハッシュのコストが現在の設定と異なるかを返します
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+// initPasswordHasher はパスワードハッシュの設定を反映します
+func initPasswordHasher(cfg config.PasswordHashConfig) {
+	bcryptHasher := BcryptHasher{Cost: 14}
+	if cfg.BcryptCost >= bcrypt.MinCost && cfg.BcryptCost <= bcrypt.MaxCost {
+		bcryptHasher.Cost = cfg.BcryptCost
+	}
+	argon2idHasher := NewArgon2idHasher(cfg)
+
+	switch strings.ToLower(cfg.Algorithm) {
+	case HashAlgorithmBcrypt:
+		passwordHasher = bcryptHasher
+		legacyHashers = []PasswordHasher{argon2idHasher}
+	case HashAlgorithmArgon2id, "":
+		passwordHasher = argon2idHasher
+		legacyHashers = []PasswordHasher{bcryptHasher}
+	default:
+		log.Printf("Warning: Unknown PASSWORD_HASH_ALGORITHM %q. Using %s.", cfg.Algorithm, HashAlgorithmArgon2id)
+		passwordHasher = argon2idHasher
+		legacyHashers = []PasswordHasher{bcryptHasher}
+	}
 }
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"fmt"
+	"testing"
+
+	"backend/config"
+)
+
+// testHashConfig はテストを速くするため小さいパラメータにした設定です
+var testHashConfig = config.PasswordHashConfig{
+	Algorithm:         HashAlgorithmArgon2id,
+	Argon2Memory:      8 * 1024,
+	Argon2Iterations:  1,
+	Argon2Parallelism: 1,
+	BcryptCost:        4,
+}
+
+// setPasswordHashConfig はパスワードハッシュの設定を変更し、テスト終了時に元に戻します
+func setPasswordHashConfig(t *testing.T, cfg config.PasswordHashConfig) {
+	t.Helper()
+	hasher, legacy := passwordHasher, legacyHashers
+	t.Cleanup(func() {
+		passwordHasher, legacyHashers = hasher, legacy
+	})
+	initPasswordHasher(cfg)
+}
+
+func TestVerifyPasswordBcryptNeedsRehash(t *testing.T) {
+	setPasswordHashConfig(t, testHashConfig)
+
+	hash, err := BcryptHasher{Cost: 4}.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, needsRehash := VerifyPassword("correct horse", hash)
+	if !ok || !needsRehash {
+		t.Errorf("VerifyPassword() = %v, %v, want true, true", ok, needsRehash)
+	}
+	if ok, needsRehash := VerifyPassword("wrong", hash); ok || needsRehash {
+		t.Errorf("VerifyPassword(wrong) = %v, %v, want false, false", ok, needsRehash)
+	}
+
+	// 作り直したハッシュは現在のアルゴリズムになる
+	rehashed, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, needsRehash := VerifyPassword("correct horse", rehashed); !ok || needsRehash {
+		t.Errorf("VerifyPassword(rehashed) = %v, %v, want true, false", ok, needsRehash)
+	}
+}
+
+func TestVerifyPasswordParameterChange(t *testing.T) {
+	bcryptConfig := testHashConfig
+	bcryptConfig.Algorithm = HashAlgorithmBcrypt
+
+	tests := []struct {
+		name    string
+		current config.PasswordHashConfig
+		changed func(cfg config.PasswordHashConfig) config.PasswordHashConfig
+	}{
+		{"argon2id memory", testHashConfig, func(cfg config.PasswordHashConfig) config.PasswordHashConfig {
+			cfg.Argon2Memory *= 2
+			return cfg
+		}},
+		{"argon2id iterations", testHashConfig, func(cfg config.PasswordHashConfig) config.PasswordHashConfig {
+			cfg.Argon2Iterations++
+			return cfg
+		}},
+		{"argon2id parallelism", testHashConfig, func(cfg config.PasswordHashConfig) config.PasswordHashConfig {
+			cfg.Argon2Parallelism++
+			return cfg
+		}},
+		{"bcrypt cost", bcryptConfig, func(cfg config.PasswordHashConfig) config.PasswordHashConfig {
+			cfg.BcryptCost++
+			return cfg
+		}},
+		{"algorithm", testHashConfig, func(cfg config.PasswordHashConfig) config.PasswordHashConfig {
+			cfg.Algorithm = HashAlgorithmBcrypt
+			return cfg
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPasswordHashConfig(t, tt.current)
+			hash, err := HashPassword("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, needsRehash := VerifyPassword("correct horse", hash); !ok || needsRehash {
+				t.Errorf("before change VerifyPassword() = %v, %v, want true, false", ok, needsRehash)
+			}
+
+			initPasswordHasher(tt.changed(tt.current))
+			if ok, needsRehash := VerifyPassword("correct horse", hash); !ok || !needsRehash {
+				t.Errorf("after change VerifyPassword() = %v, %v, want true, true", ok, needsRehash)
+			}
+		})
+	}
+}
+
+func BenchmarkArgon2idHash(b *testing.B) {
+	params := []struct {
+		memory      uint32
+		iterations  uint32
+		parallelism uint8
+	}{
+		{19 * 1024, 2, 1}, // OWASPの最小推奨値
+		{46 * 1024, 1, 1},
+		{64 * 1024, 3, 2}, // 既定値
+		{128 * 1024, 3, 4},
+	}
+	for _, p := range params {
+		hasher := NewArgon2idHasher(config.PasswordHashConfig{
+			Argon2Memory:      p.memory,
+			Argon2Iterations:  p.iterations,
+			Argon2Parallelism: p.parallelism,
+		})
+		b.Run(fmt.Sprintf("m=%d,t=%d,p=%d", p.memory, p.iterations, p.parallelism), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := hasher.Hash("correct horse battery staple"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBcryptHash(b *testing.B) {
+	for _, cost := range []int{10, 12, 14} {
+		hasher := BcryptHasher{Cost: cost}
+		b.Run(fmt.Sprintf("cost=%d", cost), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := hasher.Hash("correct horse battery staple"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
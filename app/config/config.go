@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,7 +14,10 @@ import (
 type Config struct {
 	Database DatabaseConfig
 	JWT      JWTConfig
+	Auth     AuthConfig
+	Mail     MailConfig
 	Server   ServerConfig
+	OIDC     []OIDCProviderConfig
 }
 
 // DatabaseConfig はデータベース設定
@@ -25,13 +31,85 @@ type DatabaseConfig struct {
 
 // JWTConfig はJWT設定
 type JWTConfig struct {
-	Secret string
+	Secret               string        // HS256の秘密鍵（非対称鍵が未設定の場合、またはLegacyHMACUntilまでkidのない以前のトークンの検証に使用）
+	SigningKeyFile       string        // 署名に使うRSAまたはEd25519の秘密鍵のPEMファイル
+	LegacyHMACUntil      time.Time     // 非対称鍵に切り替えた後もkidのないHS256のトークンを受け付ける期限（起動からアクセストークンの有効期間まで）
+	RetiredKeyFiles      []string      // ローテーションで退役した検証専用の鍵のPEMファイル
+	Issuer               string        // iss（設定した場合は検証で必須）
+	Audience             string        // aud（設定した場合は検証で必須）
+	Leeway               time.Duration // exp・nbf・iatの検証で許容する時計のずれ
+	AccessTokenTTL       time.Duration // アクセストークンの有効期間
+	RefreshTokenTTL      time.Duration // リフレッシュトークンの有効期間
+	RevocationCacheTTL   time.Duration // 失効状態をメモリにキャッシュする期間
+	SessionTouchInterval time.Duration // セッションの最終アクセス日時を更新する最小間隔
+}
+
+// AuthConfig はアカウント管理の設定
+type AuthConfig struct {
+	PasswordResetTTL          time.Duration // パスワードリセットトークンの有効期間
+	EmailVerificationTTL      time.Duration // メールアドレス確認トークンの有効期間
+	EmailVerificationCooldown time.Duration // 確認メールを再送できるまでの間隔
+	EmailVerificationPolicy   string        // 未確認アカウントの制限（none, restrict, strict）
+	AdminUsername             string        // 管理者がいない場合に起動時に管理者にするユーザー名
+	MFAIssuer                 string        // 認証アプリに表示する発行者名
+	MFAChallengeTTL           time.Duration // パスワード認証後に2要素目を入力できる期間
+	MFASecretKey              string        // 認証アプリの秘密鍵を暗号化するAES-256の鍵（32バイトをBase64でエンコード、未設定の場合は暗号化しない）
+	OIDCStateTTL              time.Duration // 外部プロバイダーの認可リクエストを開始してからコールバックまでの期間
+	MagicLinkTTL              time.Duration // ログインリンクの有効期間
+	MagicLinkCooldown         time.Duration // 同じメールアドレスにログインリンクを再送できるまでの間隔
+	MagicLinkAutoCreate       bool          // 未登録のメールアドレスのログインリンクでアカウントを作成するか
+	LoginLimit                LoginLimitConfig
+	PasswordHash              PasswordHashConfig
+}
+
+// PasswordHashConfig はパスワードハッシュの設定
+type PasswordHashConfig struct {
+	Algorithm         string // 新しいハッシュのアルゴリズム（argon2id, bcrypt）
+	Argon2Memory      uint32 // argon2idのメモリ使用量（KiB）
+	Argon2Iterations  uint32 // argon2idの反復回数
+	Argon2Parallelism uint8  // argon2idの並列度
+	BcryptCost        int    // bcryptのコスト
+}
+
+// LoginLimitConfig はログイン試行回数の制限設定
+type LoginLimitConfig struct {
+	Store         string        // 失敗回数の保存先（postgres, memory）
+	MaxAttempts   int           // ユーザー名ごとにバックオフなしで失敗できる回数
+	IPMaxAttempts int           // IPアドレスごとにバックオフなしで失敗できる回数
+	BaseDelay     time.Duration // 最初のロック期間（以降は失敗ごとに2倍）
+	MaxLockout    time.Duration // ロック期間の上限
+	Window        time.Duration // 最後の失敗からこの期間が経過すると失敗回数をリセット
+}
+
+// MailConfig はメール送信設定
+type MailConfig struct {
+	Driver       string // smtp, file, log のいずれか
+	From         string
+	AppURL       string // メール本文のリンクの基準URL（フロントエンド）
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string // fileドライバーの出力先ディレクトリ
+}
+
+// OIDCProviderConfig はOpenID Connectプロバイダー（Google、Appleなど）の設定
+type OIDCProviderConfig struct {
+	Name         string   // URLで使うプロバイダー名（例: google）
+	IssuerURL    string   // iss（/.well-known/openid-configurationの取得に使用）
+	ClientID     string   // プロバイダーに登録したクライアントID（IDトークンのaud）
+	ClientSecret string   // クライアントシークレット（公開クライアントの場合は空）
+	RedirectURL  string   // プロバイダーに登録したコールバックURL（フロントエンド）
+	Scopes       []string // 要求するスコープ（openidは必須）
 }
 
 // ServerConfig はサーバー設定
 type ServerConfig struct {
 	Port         string
 	AllowOrigins []string
+	// X-Forwarded-ForなどのヘッダーからクライアントのIPアドレスを取得してよいリバースプロキシのIPアドレスまたはCIDR
+	// 未設定の場合はヘッダーを信頼せず、接続元のIPアドレスをそのまま使う（ログイン試行の制限とセッションのIPアドレスに影響）
+	TrustedProxies []string
 }
 
 // Load は設定を読み込みます
@@ -50,10 +128,60 @@ func Load() *Config {
 			Name:     getEnv("DB_NAME", ""),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key-change-this-in-production"),
+			Secret:               getEnv("JWT_SECRET", "your-secret-key-change-this-in-production"),
+			SigningKeyFile:       getEnv("JWT_SIGNING_KEY_FILE", ""),
+			LegacyHMACUntil:      getEnvTime("JWT_LEGACY_HMAC_UNTIL"),
+			RetiredKeyFiles:      getEnvList("JWT_RETIRED_KEY_FILES"),
+			Issuer:               getEnv("JWT_ISSUER", ""),
+			Audience:             getEnv("JWT_AUDIENCE", ""),
+			Leeway:               getEnvDuration("JWT_LEEWAY", 30*time.Second),
+			AccessTokenTTL:       getEnvDuration("JWT_ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL:      getEnvDuration("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			RevocationCacheTTL:   getEnvDuration("JWT_REVOCATION_CACHE_TTL", 30*time.Second),
+			SessionTouchInterval: getEnvDuration("SESSION_TOUCH_INTERVAL", 5*time.Minute),
+		},
+		Auth: AuthConfig{
+			PasswordResetTTL:          getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+			EmailVerificationTTL:      getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+			EmailVerificationCooldown: getEnvDuration("EMAIL_VERIFICATION_COOLDOWN", time.Minute),
+			EmailVerificationPolicy:   getEnv("EMAIL_VERIFICATION_POLICY", "restrict"),
+			AdminUsername:             getEnv("ADMIN_USERNAME", ""),
+			MFAIssuer:                 getEnv("MFA_ISSUER", "Tango"),
+			MFAChallengeTTL:           getEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+			MFASecretKey:              getEnv("MFA_SECRET_KEY", ""),
+			OIDCStateTTL:              getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+			MagicLinkTTL:              getEnvDuration("MAGIC_LINK_TTL", 15*time.Minute),
+			MagicLinkCooldown:         getEnvDuration("MAGIC_LINK_COOLDOWN", time.Minute),
+			MagicLinkAutoCreate:       getEnvBool("MAGIC_LINK_AUTO_CREATE", false),
+			LoginLimit: LoginLimitConfig{
+				Store:         getEnv("LOGIN_LIMIT_STORE", "postgres"),
+				MaxAttempts:   getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+				IPMaxAttempts: getEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+				BaseDelay:     getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+				MaxLockout:    getEnvDuration("LOGIN_MAX_LOCKOUT", 15*time.Minute),
+				Window:        getEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+			},
+			PasswordHash: PasswordHashConfig{
+				Algorithm:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+				Argon2Memory:      uint32(getEnvInt("ARGON2_MEMORY", 64*1024)),
+				Argon2Iterations:  uint32(getEnvInt("ARGON2_ITERATIONS", 3)),
+				Argon2Parallelism: uint8(min(getEnvInt("ARGON2_PARALLELISM", 2), 255)),
+				BcryptCost:        getEnvInt("BCRYPT_COST", 14),
+			},
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "no-reply@tango.fumi042-server.top"),
+			AppURL:       getEnv("APP_URL", "http://localhost:3000"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", "tmp/mail"),
 		},
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			TrustedProxies: getEnvList("TRUSTED_PROXIES"),
 			AllowOrigins: []string{
 				"http://localhost:3000",
 				"http://127.0.0.1:3000",
@@ -65,16 +193,48 @@ func Load() *Config {
 				"*",                                // 開発時のみ - 本番環境では削除してください
 			},
 		},
+		OIDC: loadOIDCProviders(),
 	}
 
 	// JWT秘密鍵の警告
-	if config.JWT.Secret == "your-secret-key-change-this-in-production" {
+	// 非対称鍵を使う場合、既定の秘密鍵で署名されたトークンは誰でも偽造できるため受け付けない
+	if config.JWT.SigningKeyFile != "" && config.JWT.Secret == "your-secret-key-change-this-in-production" {
+		config.JWT.Secret = ""
+	}
+	if config.JWT.SigningKeyFile == "" && config.JWT.Secret == "your-secret-key-change-this-in-production" {
 		log.Println("Warning: Using default JWT secret. Set JWT_SECRET environment variable in production.")
 	}
 
 	return config
 }
 
+// loadOIDCProviders はOIDC_PROVIDERSに列挙したプロバイダーの設定を読み込みます
+// 各プロバイダーの設定はOIDC_<NAME>_ISSUER、OIDC_<NAME>_CLIENT_IDなどの環境変数で指定します
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range getEnvList("OIDC_PROVIDERS") {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       getEnvList(prefix + "SCOPES"),
+		}
+		if provider.IssuerURL == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			log.Printf("Warning: OIDC provider %s requires %sISSUER, %sCLIENT_ID and %sREDIRECT_URL. Skipping.", name, prefix, prefix, prefix)
+			continue
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
 // getEnv は環境変数を取得し、存在しない場合はデフォルト値を返します
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -82,3 +242,70 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration は環境変数を時間（例: 15m, 720h）として取得し、存在しないか不正な場合はデフォルト値を返します
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Warning: Invalid %s %q. Using default %s.", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getEnvInt は環境変数を正の整数として取得し、存在しないか不正な場合はデフォルト値を返します
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Warning: Invalid %s %q. Using default %d.", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvBool は環境変数を真偽値（true, false, 1, 0など）として取得し、存在しないか不正な場合はデフォルト値を返します
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: Invalid %s %q. Using default %t.", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// getEnvTime は環境変数を日時（RFC 3339、例: 2024-01-01T09:00:00+09:00）として取得し、存在しないか不正な場合はゼロ値を返します
+func getEnvTime(key string) time.Time {
+	value := os.Getenv(key)
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Printf("Warning: Invalid %s %q. Ignoring.", key, value)
+		return time.Time{}
+	}
+	return t
+}
+
+// getEnvList は環境変数をカンマ区切りのリストとして取得します
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -31,10 +31,17 @@ func Connect(dbConfig config.DatabaseConfig) error {
 	}
 
 	// マイグレーション実行
-	if err := DB.AutoMigrate(&models.User{}, &models.Word{}); err != nil {
+	if err := DB.AutoMigrate(&models.User{}, &models.Category{}, &models.Word{}, &models.WordProgress{},
+		&models.Deck{}, &models.DeckWord{}, &models.QuizSession{}, &models.QuizQuestion{}, &models.RefreshToken{}, &models.RevokedToken{},
+		&models.Session{}, &models.PasswordResetToken{}, &models.EmailVerificationToken{}, &models.LoginAttempt{},
+		&models.TOTPCredential{}, &models.RecoveryCode{}, &models.MFAChallenge{}, &models.APIKey{},
+		&models.OIDCIdentity{}, &models.OIDCLoginState{}, &models.MagicLinkToken{}); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// 外部キー制約を追加
+	addForeignKeys()
+
 	log.Println("Successfully connected to database and migrated tables")
 	return nil
 }
@@ -85,9 +92,79 @@ func customMigration() error {
 			log.Println("Added difficulty_level check constraint")
 		}
 	}
+
+	// email_verified_at導入前に登録されたユーザーは確認済みとして扱う
+	if DB.Migrator().HasTable(&models.User{}) && !DB.Migrator().HasColumn(&models.User{}, "email_verified_at") {
+		if err := DB.Exec("ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ").Error; err != nil {
+			return err
+		}
+		if err := DB.Exec("UPDATE users SET email_verified_at = created_at").Error; err != nil {
+			return err
+		}
+		log.Println("Added email_verified_at column to users table")
+	}
 	return nil
 }
 
+// foreignKey は追加する外部キー制約の定義です
+type foreignKey struct {
+	table      string
+	name       string
+	definition string
+}
+
+// addForeignKeys はモデルのタグで表現していない外部キー制約を追加します
+func addForeignKeys() {
+	foreignKeys := []foreignKey{
+		{"categories", "fk_categories_parent", "FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE RESTRICT"},
+		{"words", "fk_words_main_category", "FOREIGN KEY (main_category_id) REFERENCES categories(id) ON DELETE RESTRICT"},
+		{"words", "fk_words_sub_category", "FOREIGN KEY (sub_category_id) REFERENCES categories(id) ON DELETE RESTRICT"},
+		{"words", "fk_words_owner", "FOREIGN KEY (owner_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"word_progress", "fk_word_progress_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"word_progress", "fk_word_progress_word", "FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE"},
+		{"quiz_sessions", "fk_quiz_sessions_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"quiz_sessions", "fk_quiz_sessions_deck", "FOREIGN KEY (deck_id) REFERENCES decks(id) ON DELETE SET NULL"},
+		{"quiz_questions", "fk_quiz_questions_session", "FOREIGN KEY (session_id) REFERENCES quiz_sessions(id) ON DELETE CASCADE"},
+		{"decks", "fk_decks_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"deck_words", "fk_deck_words_deck", "FOREIGN KEY (deck_id) REFERENCES decks(id) ON DELETE CASCADE"},
+		{"deck_words", "fk_deck_words_word", "FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE"},
+		{"refresh_tokens", "fk_refresh_tokens_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"revoked_tokens", "fk_revoked_tokens_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"sessions", "fk_sessions_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"password_reset_tokens", "fk_password_reset_tokens_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"email_verification_tokens", "fk_email_verification_tokens_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"totp_credentials", "fk_totp_credentials_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"recovery_codes", "fk_recovery_codes_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"mfa_challenges", "fk_mfa_challenges_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"api_keys", "fk_api_keys_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"oidc_identities", "fk_oidc_identities_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+		{"magic_link_tokens", "fk_magic_link_tokens_user", "FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE"},
+	}
+
+	for _, fk := range foreignKeys {
+		if err := addConstraintIfNotExists(fk.table, fk.name, fk.definition); err != nil {
+			log.Printf("Warning: Failed to add constraint %s: %v", fk.name, err)
+		}
+	}
+}
+
+// addConstraintIfNotExists はテーブルに制約が存在しない場合のみ追加します
+func addConstraintIfNotExists(table, name, definition string) error {
+	sql := fmt.Sprintf(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.table_constraints
+				WHERE constraint_name = '%s'
+				AND table_name = '%s'
+			) THEN
+				ALTER TABLE %s ADD CONSTRAINT %s %s;
+			END IF;
+		END $$;
+	`, name, table, table, name, definition)
+	return DB.Exec(sql).Error
+}
+
 // Close はデータベース接続を閉じます
 func Close() error {
 	if DB != nil {
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"backend/auth"
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errLastAdmin は最後の管理者を降格・削除しようとした場合のエラーです
+var errLastAdmin = errors.New("cannot remove the last admin")
+
+// findSystemWord はシステム単語をIDで取得します
+// 存在しない場合や個人の単語の場合は404を返してfalseを返します
+func findSystemWord(c *gin.Context, id uint) (*models.Word, bool) {
+	var word models.Word
+	err := database.GetDB().Where("id = ? AND is_system = ?", id, true).First(&word).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch word"})
+		return nil, false
+	}
+	return &word, true
+}
+
+// CreateSystemWordHandler はシステム単語の作成ハンドラーです（編集者用）
+func CreateSystemWordHandler(c *gin.Context) {
+	req, ok := bindWordRequest(c)
+	if !ok {
+		return
+	}
+	if req.IsSystem != nil && !*req.IsSystem {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Personal words cannot be created by this endpoint"})
+		return
+	}
+
+	createWord(c, req, nil)
+}
+
+// UpdateSystemWordHandler はシステム単語の更新ハンドラーです（編集者用）
+func UpdateSystemWordHandler(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req, ok := bindWordRequest(c)
+	if !ok {
+		return
+	}
+	if req.IsSystem != nil && !*req.IsSystem {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "System words cannot be converted to personal words"})
+		return
+	}
+
+	word, ok := findSystemWord(c, id)
+	if !ok {
+		return
+	}
+
+	updateWord(c, word, req)
+}
+
+// DeleteSystemWordHandler はシステム単語の削除ハンドラーです（編集者用）
+func DeleteSystemWordHandler(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	word, ok := findSystemWord(c, id)
+	if !ok {
+		return
+	}
+
+	deleteWord(c, word)
+}
+
+// ImportSystemWordsHandler はシステム単語のCSV/TSV一括インポートハンドラーです（編集者用）
+func ImportSystemWordsHandler(c *gin.Context) {
+	importWords(c, nil)
+}
+
+// ListUsersHandler はユーザー一覧取得ハンドラーです（管理者用）
+// roleで役割、qでユーザー名・メールアドレスの部分一致に絞り込めます
+func ListUsersHandler(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := database.GetDB().Model(&models.User{})
+	if role := c.Query("role"); role != "" {
+		if !isValidRole(role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Must be user, editor, or admin"})
+			return
+		}
+		query = query.Where("role = ?", role)
+	}
+	if q := c.Query("q"); q != "" {
+		pattern := containsPattern(q)
+		query = query.Where(`username ILIKE ? ESCAPE '\' OR email ILIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+		return
+	}
+
+	var users []models.User
+	if err := query.Order("user_id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	responses := make([]models.UserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, users[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":  responses,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetUserHandler はユーザー詳細取得ハンドラーです（管理者用）
+func GetUserHandler(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	user, ok := findUser(c, id)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": user.ToResponse(),
+	})
+}
+
+// UpdateUserRoleHandler はユーザーの役割変更ハンドラーです（管理者用）
+// 変更後はそのユーザーの全てのトークンを失効させ、新しい役割で再ログインさせます
+func UpdateUserRoleHandler(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !isValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Must be user, editor, or admin"})
+		return
+	}
+
+	user, ok := findUser(c, id)
+	if !ok {
+		return
+	}
+	if req.Role == user.Role {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Role unchanged",
+			"user":    user.ToResponse(),
+		})
+		return
+	}
+
+	now := time.Now()
+	var version int
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if user.Role == models.RoleAdmin {
+			if err := ensureOtherAdmin(tx, user.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(user).Update("role", req.Role).Error; err != nil {
+			return err
+		}
+
+		// 役割はアクセストークンのクレームに含まれるため、発行済みのトークンを失効させる
+		var err error
+		version, err = auth.RevokeAllTokensTx(tx, user.ID, now)
+		return err
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot demote the last admin"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	user.Role = req.Role
+	auth.CacheTokenVersion(user.ID, version, now)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"user":    user.ToResponse(),
+	})
+}
+
+// DeleteUserHandler はユーザー削除ハンドラーです（管理者用）
+// ユーザーの単語・デッキ・学習履歴・トークンも削除されます
+func DeleteUserHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if id == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete your own account"})
+		return
+	}
+
+	user, ok := findUser(c, id)
+	if !ok {
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if user.Role == models.RoleAdmin {
+			if err := ensureOtherAdmin(tx, user.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(user).Error
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last admin"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deleted successfully",
+	})
+}
+
+// findUser はユーザーをIDで取得します
+// 存在しない場合は404を返してfalseを返します
+func findUser(c *gin.Context, id uint) (*models.User, bool) {
+	var user models.User
+	err := database.GetDB().Where("user_id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// isValidRole は役割が定義済みのものかを返します
+func isValidRole(role string) bool {
+	switch role {
+	case models.RoleUser, models.RoleEditor, models.RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// ensureOtherAdmin はuserID以外に管理者が存在するかを確認します
+// 同時に2人の管理者が互いを降格させないよう、管理者の行をロックします
+func ensureOtherAdmin(tx *gorm.DB, userID uint) error {
+	var ids []uint
+	err := tx.Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ?", models.RoleAdmin).Pluck("user_id", &ids).Error
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id != userID {
+			return nil
+		}
+	}
+	return errLastAdmin
+}
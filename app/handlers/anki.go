@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/anki"
+	"backend/database"
+	"backend/models"
+	"backend/srs"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxAnkiImportBytes はapkgファイルの最大サイズです（メディアを含むため大きめにする）
+const maxAnkiImportBytes = 100 << 20 // 100MB
+
+// ankiGUIDPrefix はエクスポートしたノートのGUIDの接頭辞です
+// 同じ単語を再エクスポートした場合にAnki側で同じノートとして更新されるようにします
+const ankiGUIDPrefix = "vocab-"
+
+// ankiImportNote はインポート対象のノートと対応する単語です
+type ankiImportNote struct {
+	req  *models.WordRequest
+	card *anki.Card
+	word *models.Word
+}
+
+// readAnkiPackage はmultipartのfileフィールドからapkgファイルを読み込みます
+func readAnkiPackage(c *gin.Context) (*anki.Package, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAnkiImportBytes)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read apkg file"})
+		return nil, false
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read apkg file"})
+		return nil, false
+	}
+	defer file.Close()
+
+	pkg, err := anki.Read(file, header.Size)
+	if errors.Is(err, anki.ErrUnsupportedFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported apkg format. Export from Anki with \"Support older Anki versions\" enabled"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid apkg file"})
+		return nil, false
+	}
+	return pkg, true
+}
+
+// ankiDeckName はインポート先のデッキ名を決めます（nameフォーム値 > パッケージのデッキ名）
+func ankiDeckName(name, packageName string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = strings.TrimSpace(packageName)
+	}
+	if name == "" {
+		name = "Anki import"
+	}
+	for len(name) > 100 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// parseAnkiNotes はノートを単語リクエストに変換します
+// 表面を単語、裏面を日本語の意味として扱い、変換できないノートと重複はスキップします
+func parseAnkiNotes(pkg *anki.Package) ([]*ankiImportNote, []ImportRowError) {
+	var (
+		notes   []*ankiImportNote
+		skipped []ImportRowError
+	)
+	seen := make(map[string]int)
+
+	for i := range pkg.Notes {
+		row := i + 1
+		note := &pkg.Notes[i]
+		req := &models.WordRequest{Word: note.Front()}
+		if back := note.Back(); back != "" {
+			req.JapaneseMeaning = &back
+		}
+		if err := validateWordRequest(req); err != nil {
+			skipped = append(skipped, ImportRowError{Row: row, Word: req.Word, Errors: []string{err.Error()}})
+			continue
+		}
+
+		key := strings.ToLower(req.Word)
+		if first, ok := seen[key]; ok {
+			skipped = append(skipped, ImportRowError{Row: row, Word: req.Word, Errors: []string{fmt.Sprintf("duplicate of note %d", first)}})
+			continue
+		}
+		seen[key] = row
+		notes = append(notes, &ankiImportNote{req: req, card: note.Card})
+	}
+	return notes, skipped
+}
+
+// ankiCard はAnkiの学習状態をSM-2のカード状態に変換します
+// Ankiは連続正解数を保持しないため、復習カードは復習回数から忘却回数を引いた値で近似します
+func ankiCard(card *anki.Card) srs.Card {
+	result := srs.SM2{}.NewCard()
+	if card.EaseFactor > 0 {
+		result.EaseFactor = min(max(card.EaseFactor, srs.MinEaseFactor), srs.MaxEaseFactor)
+	}
+	result.IntervalDays = card.Interval
+	result.Lapses = card.Lapses
+	result.LastReviewedAt = card.LastReview
+	if card.Type == anki.CardTypeReview {
+		result.Repetitions = max(card.Reps-card.Lapses, 1)
+	}
+	return result
+}
+
+// ImportAnkiDeckHandler はAnkiのapkgファイルをデッキとしてインポートするハンドラーです
+// ノートは自分の単語として登録し（閲覧可能な同じ綴りの単語があれば再利用）、
+// 学習済みのカードは復習履歴を初期の学習状態として引き継ぎます
+func ImportAnkiDeckHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	pkg, ok := readAnkiPackage(c)
+	if !ok {
+		return
+	}
+
+	notes, skipped := parseAnkiNotes(pkg)
+	if len(notes) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "apkg does not contain any importable notes",
+			"skipped": skipped,
+		})
+		return
+	}
+	if len(notes) > maxDeckWords {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errDeckFull.Error()})
+		return
+	}
+
+	now := time.Now()
+	deck := models.Deck{
+		UserID:    userID,
+		Name:      ankiDeckName(c.PostForm("name"), pkg.DeckName),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	created, reused, progressImported := 0, 0, 0
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		scheduler, err := userScheduler(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		// 閲覧可能な同じ綴りの単語を取得（自分の単語を優先）
+		keys := make([]string, 0, len(notes))
+		for _, note := range notes {
+			keys = append(keys, strings.ToLower(note.req.Word))
+		}
+		var existing []models.Word
+		if err := tx.Scopes(visibleWords(userID)).Where("LOWER(word) IN ?", keys).Order("is_system, id").Find(&existing).Error; err != nil {
+			return err
+		}
+		existingMap := make(map[string]*models.Word, len(existing))
+		for i := range existing {
+			key := strings.ToLower(existing[i].Word)
+			if existingMap[key] == nil {
+				existingMap[key] = &existing[i]
+			}
+		}
+
+		var newWords []models.Word
+		for _, note := range notes {
+			if word := existingMap[strings.ToLower(note.req.Word)]; word != nil {
+				note.word = word
+				reused++
+				continue
+			}
+			newWords = append(newWords, models.Word{
+				Word:            note.req.Word,
+				JapaneseMeaning: note.req.JapaneseMeaning,
+				OwnerID:         &userID,
+				CreatedAt:       now,
+				UpdatedAt:       now,
+			})
+		}
+		if len(newWords) > 0 {
+			if err := tx.CreateInBatches(&newWords, exportBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		next := 0
+		for _, note := range notes {
+			if note.word == nil {
+				note.word = &newWords[next]
+				next++
+			}
+		}
+		created = len(newWords)
+
+		if err := tx.Create(&deck).Error; err != nil {
+			return err
+		}
+		deckWords := make([]models.DeckWord, 0, len(notes))
+		for i, note := range notes {
+			deckWords = append(deckWords, models.DeckWord{DeckID: deck.ID, WordID: note.word.ID, Position: i + 1, AddedAt: now})
+		}
+		if err := tx.CreateInBatches(&deckWords, exportBatchSize).Error; err != nil {
+			return err
+		}
+
+		// 学習済みのカードのみ学習状態を作成し、既存の学習状態は上書きしない
+		var learned []uint
+		if err := tx.Model(&models.WordProgress{}).Where("user_id = ? AND word_id IN (?)", userID, tx.Model(&models.DeckWord{}).Select("word_id").Where("deck_id = ?", deck.ID)).Pluck("word_id", &learned).Error; err != nil {
+			return err
+		}
+		hasProgress := make(map[uint]bool, len(learned))
+		for _, wordID := range learned {
+			hasProgress[wordID] = true
+		}
+
+		var progresses []models.WordProgress
+		for _, note := range notes {
+			card := note.card
+			if card == nil || card.Type == anki.CardTypeNew || card.DueAt == nil || hasProgress[note.word.ID] {
+				continue
+			}
+			progress := models.WordProgress{
+				UserID:      userID,
+				WordID:      note.word.ID,
+				DueAt:       *card.DueAt,
+				ReviewCount: card.Reps,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			progress.ApplyCard(srs.Prepare(scheduler, ankiCard(card)))
+			progresses = append(progresses, progress)
+		}
+		if len(progresses) > 0 {
+			if err := tx.CreateInBatches(&progresses, exportBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		progressImported = len(progresses)
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import apkg"})
+		return
+	}
+
+	response := deck.ToResponse()
+	response.WordCount = int64(len(notes))
+	c.JSON(http.StatusCreated, gin.H{
+		"message":           "Anki deck imported successfully",
+		"deck":              response,
+		"words_created":     created,
+		"words_reused":      reused,
+		"progress_imported": progressImported,
+		"media_ignored":     pkg.MediaCount,
+		"skipped":           skipped,
+	})
+}
+
+// ExportDeckAnkiHandler はデッキをAnkiのapkgファイルとしてエクスポートするハンドラーです
+// 学習状態はSM-2の状態に変換して復習カードとして出力します
+func ExportDeckAnkiHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	words, err := loadDeckWords(deck.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deck words"})
+		return
+	}
+
+	var progresses []models.WordProgress
+	if err := database.GetDB().Where("user_id = ?", userID).Scopes(inDeck(deck.ID, "word_id")).Find(&progresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch progress"})
+		return
+	}
+	progressMap := make(map[uint]*models.WordProgress, len(progresses))
+	for i := range progresses {
+		progressMap[progresses[i].WordID] = &progresses[i]
+	}
+
+	pkg := &anki.Package{DeckName: deck.Name}
+	for i := range words {
+		word := &words[i]
+		meaning := ""
+		if word.JapaneseMeaning != nil {
+			meaning = *word.JapaneseMeaning
+		}
+		note := anki.Note{
+			GUID:   ankiGUIDPrefix + strconv.FormatUint(uint64(word.ID), 10),
+			Fields: []string{html.EscapeString(word.Word), html.EscapeString(meaning)},
+		}
+		if word.PartOfSpeech != nil {
+			note.Tags = append(note.Tags, *word.PartOfSpeech)
+		}
+		if word.Level != nil {
+			note.Tags = append(note.Tags, fmt.Sprintf("level%d", *word.Level))
+		}
+		if progress := progressMap[word.ID]; progress != nil {
+			sm2 := srs.Prepare(srs.SM2{}, progress.Card())
+			dueAt := progress.DueAt
+			note.Card = &anki.Card{
+				Type:       anki.CardTypeReview,
+				Interval:   progress.IntervalDays,
+				EaseFactor: sm2.EaseFactor,
+				Reps:       progress.ReviewCount,
+				Lapses:     progress.LapseCount,
+				DueAt:      &dueAt,
+				LastReview: progress.LastReviewedAt,
+			}
+		}
+		pkg.Notes = append(pkg.Notes, note)
+	}
+
+	var buf bytes.Buffer
+	if err := anki.Write(&buf, pkg, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export apkg"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="deck-%d.apkg"`, deck.ID))
+	c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+}
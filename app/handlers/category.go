@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errCategoryNotFound はカテゴリ検証時に参照先が存在しない場合のエラーです
+var errCategoryNotFound = errors.New("category not found")
+
+// loadCategory はIDでカテゴリを取得します
+func loadCategory(id int) (*models.Category, error) {
+	var category models.Category
+	err := database.GetDB().Where("id = ?", id).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// validateWordCategories は単語のカテゴリIDが存在し、階層が正しいかを検証します
+// 入力エラーの場合は第1戻り値にメッセージを返します
+func validateWordCategories(mainCategoryID, subCategoryID *int) (string, error) {
+	if mainCategoryID != nil {
+		main, err := loadCategory(*mainCategoryID)
+		if errors.Is(err, errCategoryNotFound) {
+			return "main_category_id does not exist", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if !main.IsMain() {
+			return "main_category_id must refer to a main category", nil
+		}
+	}
+
+	if subCategoryID != nil {
+		sub, err := loadCategory(*subCategoryID)
+		if errors.Is(err, errCategoryNotFound) {
+			return "sub_category_id does not exist", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if sub.IsMain() || mainCategoryID == nil || *sub.ParentID != *mainCategoryID {
+			return "sub_category_id must be a sub category of main_category_id", nil
+		}
+	}
+
+	return "", nil
+}
+
+// findCategory はURLパラメータのIDでカテゴリを取得します。存在しない場合は404を返してfalseを返します
+func findCategory(c *gin.Context) (*models.Category, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	category, err := loadCategory(int(id))
+	if errors.Is(err, errCategoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return nil, false
+	}
+	return category, true
+}
+
+// validateCategoryRequest はカテゴリリクエストを検証します
+// selfIDが0以外の場合は更新対象のカテゴリIDです
+func validateCategoryRequest(c *gin.Context, req *models.CategoryRequest, selfID int) bool {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid name. Must be 1 to 50 characters"})
+		return false
+	}
+
+	if req.ParentID != nil {
+		if *req.ParentID == selfID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category cannot be its own parent"})
+			return false
+		}
+
+		parent, err := loadCategory(*req.ParentID)
+		if errors.Is(err, errCategoryNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parent_id does not exist"})
+			return false
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch parent category"})
+			return false
+		}
+		// カテゴリは2階層まで
+		if !parent.IsMain() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parent_id must refer to a main category"})
+			return false
+		}
+	}
+
+	// 同じ親の下で名前が重複していないかチェック
+	query := database.GetDB().Model(&models.Category{}).Where("LOWER(name) = LOWER(?)", req.Name)
+	if req.ParentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *req.ParentID)
+	}
+	if selfID != 0 {
+		query = query.Where("id <> ?", selfID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category already exists"})
+		return false
+	}
+
+	return true
+}
+
+// ListCategoriesHandler はカテゴリ一覧取得ハンドラーです
+// メインカテゴリの下にサブカテゴリを入れ子にして返します
+func ListCategoriesHandler(c *gin.Context) {
+	var categories []models.Category
+	if err := database.GetDB().Order("id").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	children := make(map[int][]models.CategoryResponse)
+	for i := range categories {
+		if parentID := categories[i].ParentID; parentID != nil {
+			children[*parentID] = append(children[*parentID], categories[i].ToResponse())
+		}
+	}
+
+	responses := make([]models.CategoryResponse, 0)
+	for i := range categories {
+		if categories[i].IsMain() {
+			response := categories[i].ToResponse()
+			response.SubCategories = children[categories[i].ID]
+			responses = append(responses, response)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": responses,
+	})
+}
+
+// GetCategoryHandler はカテゴリ取得ハンドラーです
+func GetCategoryHandler(c *gin.Context) {
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+
+	response := category.ToResponse()
+	if category.IsMain() {
+		var subCategories []models.Category
+		if err := database.GetDB().Where("parent_id = ?", category.ID).Order("id").Find(&subCategories).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sub categories"})
+			return
+		}
+		for i := range subCategories {
+			response.SubCategories = append(response.SubCategories, subCategories[i].ToResponse())
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"category": response,
+	})
+}
+
+// CreateCategoryHandler はカテゴリ作成ハンドラーです
+func CreateCategoryHandler(c *gin.Context) {
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !validateCategoryRequest(c, &req, 0) {
+		return
+	}
+
+	now := time.Now()
+	category := models.Category{
+		Name:        req.Name,
+		Description: req.Description,
+		ParentID:    req.ParentID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := database.GetDB().Create(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Category created successfully",
+		"category": category.ToResponse(),
+	})
+}
+
+// UpdateCategoryHandler はカテゴリ更新ハンドラーです
+func UpdateCategoryHandler(c *gin.Context) {
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !validateCategoryRequest(c, &req, category.ID) {
+		return
+	}
+
+	// 親の変更は単語の階層整合性を壊すため、単語やサブカテゴリを持つ場合は禁止
+	if !sameParent(category.ParentID, req.ParentID) {
+		var subCount, wordCount int64
+		db := database.GetDB()
+		if err := db.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&subCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check sub categories"})
+			return
+		}
+		if err := db.Model(&models.Word{}).Where("main_category_id = ? OR sub_category_id = ?", category.ID, category.ID).Count(&wordCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check words"})
+			return
+		}
+		if subCount > 0 || wordCount > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot change parent of a category that has sub categories or words"})
+			return
+		}
+	}
+
+	category.Name = req.Name
+	category.Description = req.Description
+	category.ParentID = req.ParentID
+	category.UpdatedAt = time.Now()
+
+	updateData := map[string]interface{}{
+		"name":        category.Name,
+		"description": category.Description,
+		"parent_id":   category.ParentID,
+		"updated_at":  category.UpdatedAt,
+	}
+
+	if err := database.GetDB().Model(category).Updates(updateData).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Category updated successfully",
+		"category": category.ToResponse(),
+	})
+}
+
+// DeleteCategoryHandler はカテゴリ削除ハンドラーです
+// 単語またはサブカテゴリが紐づいている場合は409を返します
+func DeleteCategoryHandler(c *gin.Context) {
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	var wordCount int64
+	if err := db.Model(&models.Word{}).Where("main_category_id = ? OR sub_category_id = ?", category.ID, category.ID).Count(&wordCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check words"})
+		return
+	}
+	if wordCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Category still has words",
+			"word_count": wordCount,
+		})
+		return
+	}
+
+	var subCount int64
+	if err := db.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&subCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check sub categories"})
+		return
+	}
+	if subCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category still has sub categories"})
+		return
+	}
+
+	if err := db.Delete(category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Category deleted successfully",
+	})
+}
+
+// ListCategoryWordsHandler はカテゴリに属する単語一覧取得ハンドラーです
+func ListCategoryWordsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := database.GetDB().Model(&models.Word{}).Scopes(visibleWords(userID))
+	if category.IsMain() {
+		query = query.Where("main_category_id = ?", category.ID)
+	} else {
+		query = query.Where("sub_category_id = ?", category.ID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count words"})
+		return
+	}
+
+	var words []models.Word
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&words).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch words"})
+		return
+	}
+
+	responses := make([]models.WordResponse, 0, len(words))
+	for i := range words {
+		responses = append(responses, words[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"category": category.ToResponse(),
+		"words":    responses,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// sameParent は2つの親IDが同じかを判定します
+func sameParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
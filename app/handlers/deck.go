@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// デッキのパラメータ
+const (
+	maxDeckWords       = 1000
+	maxDeckStudyTarget = 500
+)
+
+// errDeckFull はデッキの単語数が上限を超える場合のエラーです
+var errDeckFull = errors.New("deck cannot contain more than 1000 words")
+
+// inDeck はデッキに含まれる単語に絞り込むスコープです
+// columnには単語IDを表すカラム名を指定します
+func inDeck(deckID uint, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" IN (?)", database.GetDB().Model(&models.DeckWord{}).Select("word_id").Where("deck_id = ?", deckID))
+	}
+}
+
+// findDeck はログインユーザーのデッキをURLパラメータのIDで取得します
+// 存在しない場合や他のユーザーのデッキの場合は404を返してfalseを返します
+func findDeck(c *gin.Context, userID uint) (*models.Deck, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	var deck models.Deck
+	err := database.GetDB().Where("id = ? AND user_id = ?", id, userID).First(&deck).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deck"})
+		return nil, false
+	}
+	return &deck, true
+}
+
+// bindDeckRequest はデッキリクエストを読み込んで検証します
+func bindDeckRequest(c *gin.Context) (*models.DeckRequest, bool) {
+	var req models.DeckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid name. Must be 1 to 100 characters"})
+		return nil, false
+	}
+	if req.StudyTarget != nil && (*req.StudyTarget < 1 || *req.StudyTarget > maxDeckStudyTarget) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid study_target. Must be between 1 and 500"})
+		return nil, false
+	}
+	return &req, true
+}
+
+// loadDeckWords はデッキの単語を並び順で取得します
+func loadDeckWords(deckID uint) ([]models.Word, error) {
+	var words []models.Word
+	err := database.GetDB().Model(&models.Word{}).
+		Joins("JOIN deck_words ON deck_words.word_id = words.id").
+		Where("deck_words.deck_id = ?", deckID).
+		Order("deck_words.position").
+		Find(&words).Error
+	return words, err
+}
+
+// respondDeck はデッキを単語付きで返します
+func respondDeck(c *gin.Context, status int, message string, deck *models.Deck) {
+	words, err := loadDeckWords(deck.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deck words"})
+		return
+	}
+
+	response := deck.ToResponse()
+	response.WordCount = int64(len(words))
+	response.Words = make([]models.WordResponse, 0, len(words))
+	for i := range words {
+		response.Words = append(response.Words, words[i].ToResponse())
+	}
+
+	body := gin.H{"deck": response}
+	if message != "" {
+		body["message"] = message
+	}
+	c.JSON(status, body)
+}
+
+// touchDeck はデッキの更新日時を更新します
+func touchDeck(tx *gorm.DB, deck *models.Deck) error {
+	deck.UpdatedAt = time.Now()
+	return tx.Model(deck).Update("updated_at", deck.UpdatedAt).Error
+}
+
+// ListDecksHandler はデッキ一覧取得ハンドラーです
+func ListDecksHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var decks []models.Deck
+	if err := database.GetDB().Where("user_id = ?", userID).Order("id").Find(&decks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch decks"})
+		return
+	}
+
+	// 各デッキの単語数を取得
+	type deckCount struct {
+		DeckID uint
+		Count  int64
+	}
+	var counts []deckCount
+	err := database.GetDB().Model(&models.DeckWord{}).
+		Select("deck_id, COUNT(*) AS count").
+		Where("deck_id IN (?)", database.GetDB().Model(&models.Deck{}).Select("id").Where("user_id = ?", userID)).
+		Group("deck_id").
+		Scan(&counts).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count deck words"})
+		return
+	}
+	countMap := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		countMap[count.DeckID] = count.Count
+	}
+
+	responses := make([]models.DeckResponse, 0, len(decks))
+	for i := range decks {
+		response := decks[i].ToResponse()
+		response.WordCount = countMap[decks[i].ID]
+		responses = append(responses, response)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decks": responses,
+	})
+}
+
+// GetDeckHandler はデッキ取得ハンドラーです
+func GetDeckHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	respondDeck(c, http.StatusOK, "", deck)
+}
+
+// CreateDeckHandler はデッキ作成ハンドラーです
+func CreateDeckHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	req, ok := bindDeckRequest(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	deck := models.Deck{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		StudyTarget: req.StudyTarget,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := database.GetDB().Create(&deck).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create deck"})
+		return
+	}
+
+	respondDeck(c, http.StatusCreated, "Deck created successfully", &deck)
+}
+
+// UpdateDeckHandler はデッキ更新ハンドラーです
+func UpdateDeckHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	req, ok := bindDeckRequest(c)
+	if !ok {
+		return
+	}
+
+	deck.Name = req.Name
+	deck.Description = req.Description
+	deck.StudyTarget = req.StudyTarget
+	deck.UpdatedAt = time.Now()
+
+	updateData := map[string]interface{}{
+		"name":         deck.Name,
+		"description":  deck.Description,
+		"study_target": deck.StudyTarget,
+		"updated_at":   deck.UpdatedAt,
+	}
+
+	if err := database.GetDB().Model(deck).Updates(updateData).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deck"})
+		return
+	}
+
+	respondDeck(c, http.StatusOK, "Deck updated successfully", deck)
+}
+
+// DeleteDeckHandler はデッキ削除ハンドラーです（単語自体は削除しません）
+func DeleteDeckHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("deck_id = ?", deck.ID).Delete(&models.DeckWord{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(deck).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete deck"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Deck deleted successfully",
+	})
+}
+
+// AddDeckWordsHandler はデッキへの単語追加ハンドラーです
+// 単語は指定した順でデッキの末尾に追加され、既に含まれる単語は無視されます
+func AddDeckWordsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	var req models.DeckWordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.WordIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "word_ids must not be empty"})
+		return
+	}
+
+	// 閲覧可能な単語のみ追加できる
+	var visibleCount int64
+	if err := database.GetDB().Model(&models.Word{}).Scopes(visibleWords(userID)).Where("id IN ?", req.WordIDs).Count(&visibleCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check words"})
+		return
+	}
+	if int(visibleCount) != len(uniqueIDs(req.WordIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 並び順の競合を防ぐためデッキをロック
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", deck.ID).First(&models.Deck{}).Error; err != nil {
+			return err
+		}
+
+		var existing []models.DeckWord
+		if err := tx.Where("deck_id = ?", deck.ID).Find(&existing).Error; err != nil {
+			return err
+		}
+		contained := make(map[uint]bool, len(existing))
+		position := 0
+		for _, dw := range existing {
+			contained[dw.WordID] = true
+			if dw.Position > position {
+				position = dw.Position
+			}
+		}
+
+		now := time.Now()
+		var additions []models.DeckWord
+		for _, wordID := range req.WordIDs {
+			if contained[wordID] {
+				continue
+			}
+			contained[wordID] = true
+			position++
+			additions = append(additions, models.DeckWord{DeckID: deck.ID, WordID: wordID, Position: position, AddedAt: now})
+		}
+		if len(contained) > maxDeckWords {
+			return errDeckFull
+		}
+		if len(additions) > 0 {
+			if err := tx.Create(&additions).Error; err != nil {
+				return err
+			}
+		}
+		return touchDeck(tx, deck)
+	})
+	if errors.Is(err, errDeckFull) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add words"})
+		return
+	}
+
+	respondDeck(c, http.StatusOK, "Words added successfully", deck)
+}
+
+// RemoveDeckWordHandler はデッキからの単語削除ハンドラーです
+func RemoveDeckWordHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	wordID, ok := parseIDParam(c, "word_id")
+	if !ok {
+		return
+	}
+
+	var removed int64
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("deck_id = ? AND word_id = ?", deck.ID, wordID).Delete(&models.DeckWord{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected
+		if removed == 0 {
+			return nil
+		}
+		return touchDeck(tx, deck)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove word"})
+		return
+	}
+	if removed == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not in deck"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Word removed successfully",
+	})
+}
+
+// ReorderDeckWordsHandler はデッキの単語の並び替えハンドラーです
+// word_idsにはデッキの全ての単語を新しい順序で指定します
+func ReorderDeckWordsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	var req models.DeckWordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invalid bool
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var existing []models.DeckWord
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("deck_id = ?", deck.ID).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		// 現在の単語と過不足なく一致するか確認
+		if len(existing) != len(req.WordIDs) || len(uniqueIDs(req.WordIDs)) != len(req.WordIDs) {
+			invalid = true
+			return nil
+		}
+		current := make(map[uint]bool, len(existing))
+		for _, dw := range existing {
+			current[dw.WordID] = true
+		}
+		for _, wordID := range req.WordIDs {
+			if !current[wordID] {
+				invalid = true
+				return nil
+			}
+		}
+
+		for i, wordID := range req.WordIDs {
+			if err := tx.Model(&models.DeckWord{}).Where("deck_id = ? AND word_id = ?", deck.ID, wordID).Update("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return touchDeck(tx, deck)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder words"})
+		return
+	}
+	if invalid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "word_ids must list every word in the deck exactly once"})
+		return
+	}
+
+	respondDeck(c, http.StatusOK, "Words reordered successfully", deck)
+}
+
+// GetDeckDueReviewsHandler はデッキ内の復習対象取得ハンドラーです
+// new_limitを省略した場合はデッキの学習目標数だけ未学習の単語を返します
+func GetDeckDueReviewsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	defaultNewLimit := 0
+	if deck.StudyTarget != nil {
+		defaultNewLimit = *deck.StudyTarget
+	}
+	respondDueReviews(c, userID, deck, defaultNewLimit)
+}
+
+// CreateDeckQuizHandler はデッキの単語からのクイズ作成ハンドラーです
+func CreateDeckQuizHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	deck, ok := findDeck(c, userID)
+	if !ok {
+		return
+	}
+
+	createQuiz(c, userID, deck)
+}
+
+// uniqueIDs は重複を除いたIDの一覧を返します
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// クイズのパラメータ
+const (
+	defaultQuizQuestions = 10
+	maxQuizQuestions     = 50
+	quizOptionCount      = 4
+)
+
+// errNotEnoughWords はクイズを作成するのに十分な単語がない場合のエラーです
+// 各問題にはquizOptionCount個の異なる選択肢が必要です
+var errNotEnoughWords = errors.New("not enough words with japanese_meaning to build a quiz (each question needs 4 different options)")
+
+// CreateQuizHandler はクイズセッション作成ハンドラーです
+func CreateQuizHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	createQuiz(c, userID, nil)
+}
+
+// createQuiz はリクエストの条件でクイズセッションを作成します
+// deckがnil以外の場合はデッキに含まれる単語から出題します
+func createQuiz(c *gin.Context, userID uint, deck *models.Deck) {
+	var req models.CreateQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !normalizeQuizRequest(c, &req) {
+		return
+	}
+
+	// 出題候補の単語を選択
+	query := database.GetDB().Model(&models.Word{}).Scopes(visibleWords(userID))
+	if req.Level != nil {
+		query = query.Where("level = ?", *req.Level)
+	}
+	if req.MainCategoryID != nil {
+		query = query.Where("main_category_id = ?", *req.MainCategoryID)
+	}
+	if req.PartOfSpeech != nil {
+		query = query.Where("part_of_speech = ?", *req.PartOfSpeech)
+	}
+	var deckID *uint
+	if deck != nil {
+		query = query.Scopes(inDeck(deck.ID, "id"))
+		deckID = &deck.ID
+	}
+
+	session, err := createQuizSession(query, userID, deckID, req.Direction, req.QuestionCount)
+	if errors.Is(err, errNotEnoughWords) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create quiz"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Quiz created successfully",
+		"quiz":    session.ToResponse(),
+	})
+}
+
+// normalizeQuizRequest はクイズ作成リクエストを検証し、既定値を設定します
+func normalizeQuizRequest(c *gin.Context, req *models.CreateQuizRequest) bool {
+	if req.QuestionCount == 0 {
+		req.QuestionCount = defaultQuizQuestions
+	}
+	if req.QuestionCount < 1 || req.QuestionCount > maxQuizQuestions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question_count. Must be between 1 and 50"})
+		return false
+	}
+
+	if req.Direction == "" {
+		req.Direction = models.QuizDirectionMixed
+	}
+	switch req.Direction {
+	case models.QuizDirectionEnJa, models.QuizDirectionJaEn, models.QuizDirectionMixed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid direction. Must be EN_JA, JA_EN, or MIXED"})
+		return false
+	}
+
+	if req.PartOfSpeech != nil && !models.IsValidPartOfSpeech(*req.PartOfSpeech) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid part_of_speech"})
+		return false
+	}
+	return true
+}
+
+// createQuizSession は候補の単語からクイズセッションと問題を作成して保存します
+func createQuizSession(candidates *gorm.DB, userID uint, deckID *uint, direction string, count int) (*models.QuizSession, error) {
+	var words []models.Word
+	err := candidates.Where("japanese_meaning IS NOT NULL AND japanese_meaning <> ''").
+		Order("RANDOM()").Limit(count).Find(&words).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, errNotEnoughWords
+	}
+
+	db := database.GetDB()
+	questions := make([]models.QuizQuestion, 0, len(words))
+	for i := range words {
+		questionDirection := direction
+		if direction == models.QuizDirectionMixed {
+			questionDirection = models.QuizDirectionEnJa
+			if rand.Intn(2) == 1 {
+				questionDirection = models.QuizDirectionJaEn
+			}
+		}
+
+		question, err := buildQuizQuestion(userID, &words[i], questionDirection)
+		if err != nil {
+			return nil, err
+		}
+		question.Position = i + 1
+		questions = append(questions, *question)
+	}
+
+	session := models.QuizSession{
+		UserID:        userID,
+		DeckID:        deckID,
+		Direction:     direction,
+		Status:        models.QuizStatusInProgress,
+		QuestionCount: len(questions),
+		CreatedAt:     time.Now(),
+		Questions:     questions,
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// buildQuizQuestion は単語から問題と選択肢を作成します
+func buildQuizQuestion(userID uint, word *models.Word, direction string) (*models.QuizQuestion, error) {
+	prompt, answer := word.Word, *word.JapaneseMeaning
+	if direction == models.QuizDirectionJaEn {
+		prompt, answer = *word.JapaneseMeaning, word.Word
+	}
+
+	distractors, err := findDistractors(userID, word, direction, answer, quizOptionCount-1)
+	if err != nil {
+		return nil, err
+	}
+	if len(distractors) < quizOptionCount-1 {
+		return nil, errNotEnoughWords
+	}
+
+	options := append([]string{answer}, distractors...)
+	rand.Shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+	correctIndex := 0
+	for i, option := range options {
+		if option == answer {
+			correctIndex = i
+			break
+		}
+	}
+
+	return &models.QuizQuestion{
+		WordID:       word.ID,
+		Direction:    direction,
+		Prompt:       prompt,
+		Options:      options,
+		CorrectIndex: correctIndex,
+	}, nil
+}
+
+// findDistractors は紛らわしい誤答の選択肢を取得します
+// 品詞・レベル・カテゴリが同じ単語を優先し、足りない場合は条件を緩めます
+// 他のユーザーの単語が選択肢に含まれないよう、閲覧可能な単語のみを対象にします
+func findDistractors(userID uint, word *models.Word, direction, answer string, count int) ([]string, error) {
+	type condition struct {
+		column string
+		value  interface{}
+	}
+	var conditions []condition
+	if word.PartOfSpeech != nil {
+		conditions = append(conditions, condition{"part_of_speech", *word.PartOfSpeech})
+	}
+	if word.Level != nil {
+		conditions = append(conditions, condition{"level", *word.Level})
+	}
+	if word.MainCategoryID != nil {
+		conditions = append(conditions, condition{"main_category_id", *word.MainCategoryID})
+	}
+
+	seen := map[string]bool{answer: true}
+	distractors := make([]string, 0, count)
+
+	// 条件を末尾から1つずつ外しながら候補を集める
+	for n := len(conditions); n >= 0 && len(distractors) < count; n-- {
+		query := database.GetDB().Model(&models.Word{}).Scopes(visibleWords(userID)).
+			Where("id <> ? AND japanese_meaning IS NOT NULL AND japanese_meaning <> ''", word.ID)
+		for _, cond := range conditions[:n] {
+			query = query.Where(cond.column+" = ?", cond.value)
+		}
+
+		var candidates []models.Word
+		if err := query.Order("RANDOM()").Limit(count * 3).Find(&candidates).Error; err != nil {
+			return nil, err
+		}
+
+		for i := range candidates {
+			option := *candidates[i].JapaneseMeaning
+			if direction == models.QuizDirectionJaEn {
+				option = candidates[i].Word
+			}
+			if seen[option] {
+				continue
+			}
+			seen[option] = true
+			distractors = append(distractors, option)
+			if len(distractors) == count {
+				break
+			}
+		}
+	}
+
+	return distractors, nil
+}
+
+// findQuizSession はログインユーザーのクイズセッションを問題付きで取得します
+func findQuizSession(c *gin.Context, tx *gorm.DB, userID uint, lock bool) (*models.QuizSession, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	query := tx
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var session models.QuizSession
+	err := query.Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quiz not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quiz"})
+		return nil, false
+	}
+
+	if err := tx.Where("session_id = ?", session.ID).Order("position").Find(&session.Questions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quiz questions"})
+		return nil, false
+	}
+	return &session, true
+}
+
+// ListQuizzesHandler はクイズ結果の履歴取得ハンドラーです
+func ListQuizzesHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := database.GetDB().Model(&models.QuizSession{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count quizzes"})
+		return
+	}
+
+	var sessions []models.QuizSession
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quizzes"})
+		return
+	}
+
+	responses := make([]models.QuizSessionResponse, 0, len(sessions))
+	for i := range sessions {
+		responses = append(responses, sessions[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"quizzes": responses,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// GetQuizHandler はクイズセッション取得ハンドラーです
+func GetQuizHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	session, ok := findQuizSession(c, database.GetDB(), userID, false)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"quiz": session.ToResponse(),
+	})
+}
+
+// AnswerQuizHandler はクイズの回答ハンドラーです
+// 回答は1問につき1回のみ受け付け、採点結果と正解を返します
+func AnswerQuizHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.QuizAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record answer"})
+		return
+	}
+	defer tx.Rollback()
+
+	session, ok := findQuizSession(c, tx, userID, true)
+	if !ok {
+		return
+	}
+	if session.Status == models.QuizStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Quiz already completed"})
+		return
+	}
+
+	var question *models.QuizQuestion
+	for i := range session.Questions {
+		if session.Questions[i].ID == req.QuestionID {
+			question = &session.Questions[i]
+			break
+		}
+	}
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+	if question.AnsweredIndex != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Question already answered"})
+		return
+	}
+	if *req.AnswerIndex < 0 || *req.AnswerIndex >= len(question.Options) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer_index"})
+		return
+	}
+
+	now := time.Now()
+	isCorrect := *req.AnswerIndex == question.CorrectIndex
+	question.AnsweredIndex = req.AnswerIndex
+	question.IsCorrect = &isCorrect
+	question.AnsweredAt = &now
+
+	err := tx.Model(question).Updates(map[string]interface{}{
+		"answered_index": question.AnsweredIndex,
+		"is_correct":     question.IsCorrect,
+		"answered_at":    question.AnsweredAt,
+	}).Error
+	if err == nil && isCorrect {
+		session.CorrectCount++
+		err = tx.Model(session).Update("correct_count", session.CorrectCount).Error
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record answer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question": question.ToResponse(false),
+	})
+}
+
+// CompleteQuizHandler はクイズを終了し、結果サマリーを返すハンドラーです
+// 未回答の問題は不正解として採点します
+func CompleteQuizHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tx := database.GetDB().Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete quiz"})
+		return
+	}
+	defer tx.Rollback()
+
+	session, ok := findQuizSession(c, tx, userID, true)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.QuizStatusCompleted {
+		correct := 0
+		for _, question := range session.Questions {
+			if question.IsCorrect != nil && *question.IsCorrect {
+				correct++
+			}
+		}
+
+		now := time.Now()
+		score := 0.0
+		if session.QuestionCount > 0 {
+			score = float64(correct) * 100 / float64(session.QuestionCount)
+		}
+		session.Status = models.QuizStatusCompleted
+		session.CorrectCount = correct
+		session.Score = &score
+		session.CompletedAt = &now
+
+		err := tx.Model(session).Updates(map[string]interface{}{
+			"status":        session.Status,
+			"correct_count": session.CorrectCount,
+			"score":         session.Score,
+			"completed_at":  session.CompletedAt,
+		}).Error
+		if err == nil {
+			err = tx.Commit().Error
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete quiz"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Quiz completed",
+		"quiz":    session.ToResponse(),
+	})
+}
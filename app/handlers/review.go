@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/srs"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetDueReviewsHandler は復習期限が来た単語一覧取得ハンドラーです
+// new_limitを指定すると未学習の単語も追加で返します
+func GetDueReviewsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	respondDueReviews(c, userID, nil, 0)
+}
+
+// respondDueReviews は復習期限が来た単語と未学習の単語を返します
+// deckがnil以外の場合はデッキに含まれる単語のみを対象にします
+func respondDueReviews(c *gin.Context, userID uint, deck *models.Deck, defaultNewLimit int) {
+	limit, _, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	newLimit := defaultNewLimit
+	if raw := c.Query("new_limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 || value > maxPageLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid new_limit"})
+			return
+		}
+		newLimit = value
+	}
+
+	db := database.GetDB()
+	now := time.Now()
+
+	dueQuery := db.Model(&models.WordProgress{}).Where("user_id = ? AND due_at <= ?", userID, now)
+	if deck != nil {
+		dueQuery = dueQuery.Scopes(inDeck(deck.ID, "word_id"))
+	}
+
+	var total int64
+	if err := dueQuery.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count reviews"})
+		return
+	}
+
+	var progresses []models.WordProgress
+	if err := dueQuery.Order("due_at").Limit(limit).Find(&progresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	reviews, err := attachWords(progresses)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch words"})
+		return
+	}
+
+	newWords := make([]models.WordResponse, 0)
+	if newLimit > 0 {
+		newQuery := db.Model(&models.Word{}).Scopes(visibleWords(userID)).
+			Where("words.id NOT IN (?)", db.Model(&models.WordProgress{}).Select("word_id").Where("user_id = ?", userID))
+		if deck != nil {
+			// デッキの並び順に出題する
+			newQuery = newQuery.Joins("JOIN deck_words ON deck_words.word_id = words.id AND deck_words.deck_id = ?", deck.ID).
+				Order("deck_words.position")
+		} else {
+			newQuery = newQuery.Order("words.id")
+		}
+
+		var words []models.Word
+		if err := newQuery.Limit(newLimit).Find(&words).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch new words"})
+			return
+		}
+		for i := range words {
+			newWords = append(newWords, words[i].ToResponse())
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":   reviews,
+		"total_due": total,
+		"new_words": newWords,
+	})
+}
+
+// SubmitReviewHandler は復習結果（0〜5の評価）を記録し、次回復習日時を返すハンドラーです
+func SubmitReviewHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	wordID, ok := parseIDParam(c, "word_id")
+	if !ok {
+		return
+	}
+
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := srs.ValidateGrade(*req.Grade); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	word, ok := findWord(c, wordID, userID)
+	if !ok {
+		return
+	}
+
+	var progress models.WordProgress
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return recordReview(tx, userID, word.ID, *req.Grade, time.Now(), &progress)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record review"})
+		return
+	}
+
+	response := progress.ToResponse()
+	wordResponse := word.ToResponse()
+	response.Word = &wordResponse
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Review recorded successfully",
+		"progress": response,
+		"next_due": progress.DueAt,
+	})
+}
+
+// recordReview はトランザクション内で学習状態を読み込み、評価を反映して保存します
+func recordReview(tx *gorm.DB, userID, wordID uint, grade int, now time.Time, progress *models.WordProgress) error {
+	scheduler, err := userScheduler(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND word_id = ?", userID, wordID).
+		First(progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		*progress = models.WordProgress{
+			UserID:    userID,
+			WordID:    wordID,
+			CreatedAt: now,
+		}
+		progress.ApplyCard(scheduler.NewCard())
+	} else if err != nil {
+		return err
+	}
+
+	card, due := scheduler.Schedule(srs.Prepare(scheduler, progress.Card()), grade, now)
+	progress.ApplyCard(card)
+	progress.DueAt = due
+	progress.LastGrade = &grade
+	progress.ReviewCount++
+	progress.UpdatedAt = now
+
+	return tx.Save(progress).Error
+}
+
+// userScheduler はユーザーが選択しているスケジューラーを返します
+func userScheduler(tx *gorm.DB, userID uint) (srs.Scheduler, error) {
+	var user models.User
+	if err := tx.Select("user_id", "scheduling_algorithm").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return srs.Get(user.SchedulingAlgorithm)
+}
+
+// attachWords は学習状態に単語情報を付与したレスポンスを作成します
+func attachWords(progresses []models.WordProgress) ([]models.WordProgressResponse, error) {
+	responses := make([]models.WordProgressResponse, 0, len(progresses))
+	if len(progresses) == 0 {
+		return responses, nil
+	}
+
+	wordIDs := make([]uint, 0, len(progresses))
+	for _, p := range progresses {
+		wordIDs = append(wordIDs, p.WordID)
+	}
+
+	var words []models.Word
+	if err := database.GetDB().Where("id IN ?", wordIDs).Find(&words).Error; err != nil {
+		return nil, err
+	}
+	wordMap := make(map[uint]models.WordResponse, len(words))
+	for i := range words {
+		wordMap[words[i].ID] = words[i].ToResponse()
+	}
+
+	for i := range progresses {
+		response := progresses[i].ToResponse()
+		if word, ok := wordMap[progresses[i].WordID]; ok {
+			response.Word = &word
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
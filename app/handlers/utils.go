@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseIDParam はURLパラメータからIDを取得します
+// 不正な値の場合は400を返してfalseを返します
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// currentUserID は認証ミドルウェアが設定したユーザーIDを取得します
+// 取得できない場合はエラーレスポンスを返してfalseを返します
+func currentUserID(c *gin.Context) (uint, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return 0, false
+	}
+
+	userIDUint, ok := userID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+		return 0, false
+	}
+	return userIDUint, true
+}
+
+// parseIntQuery はクエリパラメータを整数として取得します
+// パラメータが存在しない場合はnilを返します
+func parseIntQuery(c *gin.Context, name string) (*int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// likeEscaper はLIKEのワイルドカード（%と_）とエスケープ文字をエスケープします
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// containsPattern は部分一致検索のLIKEパターンを返します（ESCAPE '\' と組み合わせて使用）
+// 検索語の%や_はワイルドカードではなく文字として扱います
+func containsPattern(q string) string {
+	return "%" + likeEscaper.Replace(q) + "%"
+}
+
+// parsePagination はlimit/offsetクエリパラメータを取得します
+func parsePagination(c *gin.Context) (int, int, bool) {
+	limit := defaultPageLimit
+	offset := 0
+
+	if raw := c.Query("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > maxPageLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit. Must be between 1 and " + strconv.Itoa(maxPageLimit)})
+			return 0, 0, false
+		}
+		limit = value
+	}
+	if raw := c.Query("offset"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return 0, 0, false
+		}
+		offset = value
+	}
+
+	return limit, offset, true
+}
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// validateWordRequest は単語リクエストの内容を検証します
+func validateWordRequest(req *models.WordRequest) error {
+	req.Word = strings.TrimSpace(req.Word)
+	if req.Word == "" {
+		return errors.New("Word must not be empty")
+	}
+	if len(req.Word) > 100 {
+		return errors.New("Word must be at most 100 characters")
+	}
+
+	if req.Level != nil && (*req.Level < models.MinWordLevel || *req.Level > models.MaxWordLevel) {
+		return fmt.Errorf("Invalid level. Must be between %d and %d", models.MinWordLevel, models.MaxWordLevel)
+	}
+
+	if req.JapaneseMeaning != nil {
+		meaning := strings.TrimSpace(*req.JapaneseMeaning)
+		if meaning == "" {
+			req.JapaneseMeaning = nil
+		} else {
+			req.JapaneseMeaning = &meaning
+		}
+	}
+
+	if req.PartOfSpeech != nil {
+		pos := strings.ToLower(strings.TrimSpace(*req.PartOfSpeech))
+		if !models.IsValidPartOfSpeech(pos) {
+			return fmt.Errorf("Invalid part_of_speech. Must be one of: %s", strings.Join(models.PartsOfSpeech, ", "))
+		}
+		req.PartOfSpeech = &pos
+	}
+
+	if req.DifficultyLevel != nil && (*req.DifficultyLevel < models.MinDifficultyLevel || *req.DifficultyLevel > models.MaxDifficultyLevel) {
+		return fmt.Errorf("Invalid difficulty_level. Must be between %d and %d", models.MinDifficultyLevel, models.MaxDifficultyLevel)
+	}
+
+	if req.MainCategoryID != nil && *req.MainCategoryID <= 0 {
+		return errors.New("Invalid main_category_id")
+	}
+	if req.SubCategoryID != nil {
+		if *req.SubCategoryID <= 0 {
+			return errors.New("Invalid sub_category_id")
+		}
+		if req.MainCategoryID == nil {
+			return errors.New("sub_category_id requires main_category_id")
+		}
+	}
+
+	return nil
+}
+
+// visibleWords はユーザーが閲覧できる単語（システム単語と自分の単語）に絞り込むスコープです
+func visibleWords(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("words.is_system = ? OR words.owner_id = ?", true, userID)
+	}
+}
+
+// scopedWords はscopeクエリパラメータ（all/system/personal）に応じた単語のクエリを返します
+func scopedWords(c *gin.Context, userID uint) (*gorm.DB, bool) {
+	query := database.GetDB().Model(&models.Word{})
+	switch c.DefaultQuery("scope", "all") {
+	case "all":
+		return query.Scopes(visibleWords(userID)), true
+	case "system":
+		return query.Where("is_system = ?", true), true
+	case "personal":
+		return query.Where("is_system = ? AND owner_id = ?", false, userID), true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope. Must be all, system, or personal"})
+		return nil, false
+	}
+}
+
+// wordExists は同じ綴りの単語が既に存在するかを確認します（excludeIDは除外）
+// ownerIDがnilの場合はシステム単語、それ以外はシステム単語とその所有者の単語を対象にします
+func wordExists(word string, ownerID *uint, excludeID uint) (bool, error) {
+	var count int64
+	query := database.GetDB().Model(&models.Word{}).Where("LOWER(word) = LOWER(?)", word)
+	if ownerID == nil {
+		query = query.Where("is_system = ?", true)
+	} else {
+		query = query.Scopes(visibleWords(*ownerID))
+	}
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// findWord はユーザーが閲覧できる単語をIDで取得します
+// 存在しない場合や他のユーザーの単語の場合は404を返してfalseを返します
+func findWord(c *gin.Context, id, userID uint) (*models.Word, bool) {
+	var word models.Word
+	err := database.GetDB().Scopes(visibleWords(userID)).Where("id = ?", id).First(&word).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch word"})
+		return nil, false
+	}
+	return &word, true
+}
+
+// findOwnWord は編集可能な（自分が所有する）単語をIDで取得します
+// システム単語の場合は403を返してfalseを返します
+func findOwnWord(c *gin.Context, id, userID uint) (*models.Word, bool) {
+	word, ok := findWord(c, id, userID)
+	if !ok {
+		return nil, false
+	}
+	if word.IsSystem || word.OwnerID == nil || *word.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "System words are read-only"})
+		return nil, false
+	}
+	return word, true
+}
+
+// ListWordsHandler は単語一覧取得ハンドラーです
+// scopeにsystemまたはpersonalを指定するとシステム単語・自分の単語のみに絞り込みます
+func ListWordsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query, ok := scopedWords(c, userID)
+	if !ok {
+		return
+	}
+
+	for _, column := range []string{"level", "difficulty_level", "main_category_id", "sub_category_id"} {
+		value, err := parseIntQuery(c, column)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + column})
+			return
+		}
+		if value != nil {
+			query = query.Where(column+" = ?", *value)
+		}
+	}
+
+	if pos := c.Query("part_of_speech"); pos != "" {
+		if !models.IsValidPartOfSpeech(pos) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid part_of_speech"})
+			return
+		}
+		query = query.Where("part_of_speech = ?", pos)
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := containsPattern(q)
+		query = query.Where(`word ILIKE ? ESCAPE '\' OR japanese_meaning ILIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count words"})
+		return
+	}
+
+	var words []models.Word
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&words).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch words"})
+		return
+	}
+
+	responses := make([]models.WordResponse, 0, len(words))
+	for i := range words {
+		responses = append(responses, words[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"words":  responses,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetWordHandler は単語取得ハンドラーです
+func GetWordHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	word, ok := findWord(c, id, userID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"word": word.ToResponse(),
+	})
+}
+
+// bindWordRequest は単語リクエストを読み込んで検証します
+func bindWordRequest(c *gin.Context) (*models.WordRequest, bool) {
+	var req models.WordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if err := validateWordRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	msg, err := validateWordCategories(req.MainCategoryID, req.SubCategoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check categories"})
+		return nil, false
+	}
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// createWord は単語を作成します。ownerIDがnilの場合はシステム単語になります
+func createWord(c *gin.Context, req *models.WordRequest, ownerID *uint) {
+	// 重複チェック
+	exists, err := wordExists(req.Word, ownerID, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check word"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "Word already exists"})
+		return
+	}
+
+	now := time.Now()
+	word := models.Word{
+		Word:            req.Word,
+		JapaneseMeaning: req.JapaneseMeaning,
+		PartOfSpeech:    req.PartOfSpeech,
+		DifficultyLevel: req.DifficultyLevel,
+		IsSystem:        ownerID == nil,
+		OwnerID:         ownerID,
+		Level:           req.Level,
+		MainCategoryID:  req.MainCategoryID,
+		SubCategoryID:   req.SubCategoryID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err := database.GetDB().Create(&word).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create word"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Word created successfully",
+		"word":    word.ToResponse(),
+	})
+}
+
+// updateWord は単語を更新します。所有者とシステム単語かどうかは変更しません
+func updateWord(c *gin.Context, word *models.Word, req *models.WordRequest) {
+	// 綴りを変更する場合は重複チェック
+	if !strings.EqualFold(word.Word, req.Word) {
+		exists, err := wordExists(req.Word, word.OwnerID, word.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check word"})
+			return
+		}
+		if exists {
+			c.JSON(http.StatusConflict, gin.H{"error": "Word already exists"})
+			return
+		}
+	}
+
+	// フィールドを更新（nilのポインタはNULLとして保存される）
+	word.Word = req.Word
+	word.JapaneseMeaning = req.JapaneseMeaning
+	word.PartOfSpeech = req.PartOfSpeech
+	word.DifficultyLevel = req.DifficultyLevel
+	word.Level = req.Level
+	word.MainCategoryID = req.MainCategoryID
+	word.SubCategoryID = req.SubCategoryID
+	word.UpdatedAt = time.Now()
+
+	updateData := map[string]interface{}{
+		"word":             word.Word,
+		"japanese_meaning": word.JapaneseMeaning,
+		"part_of_speech":   word.PartOfSpeech,
+		"difficulty_level": word.DifficultyLevel,
+		"level":            word.Level,
+		"main_category_id": word.MainCategoryID,
+		"sub_category_id":  word.SubCategoryID,
+		"updated_at":       word.UpdatedAt,
+	}
+
+	if err := database.GetDB().Model(word).Updates(updateData).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update word"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Word updated successfully",
+		"word":    word.ToResponse(),
+	})
+}
+
+// deleteWord は単語を削除します
+func deleteWord(c *gin.Context, word *models.Word) {
+	if err := database.GetDB().Delete(word).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete word"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Word deleted successfully",
+	})
+}
+
+// CreateWordHandler は自分の単語の作成ハンドラーです
+// システム単語はこのエンドポイントでは作成できません
+func CreateWordHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	req, ok := bindWordRequest(c)
+	if !ok {
+		return
+	}
+	if req.IsSystem != nil && *req.IsSystem {
+		c.JSON(http.StatusForbidden, gin.H{"error": "System words cannot be created by users"})
+		return
+	}
+
+	createWord(c, req, &userID)
+}
+
+// UpdateWordHandler は自分の単語の更新ハンドラーです
+func UpdateWordHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req, ok := bindWordRequest(c)
+	if !ok {
+		return
+	}
+	if req.IsSystem != nil && *req.IsSystem {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Personal words cannot be converted to system words"})
+		return
+	}
+
+	word, ok := findOwnWord(c, id, userID)
+	if !ok {
+		return
+	}
+
+	updateWord(c, word, req)
+}
+
+// DeleteWordHandler は自分の単語の削除ハンドラーです
+func DeleteWordHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	word, ok := findOwnWord(c, id, userID)
+	if !ok {
+		return
+	}
+
+	deleteWord(c, word)
+}
@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// インポート/エクスポートのパラメータ
+const (
+	maxImportRows    = 10000
+	maxImportBytes   = 10 << 20 // 10MB
+	exportBatchSize  = 500
+	importBatchSize  = 500
+	importColumnWord = "word"
+)
+
+// wordFileColumns はインポート/エクスポートファイルの列です
+var wordFileColumns = []string{
+	importColumnWord,
+	"japanese_meaning",
+	"part_of_speech",
+	"level",
+	"difficulty_level",
+	"main_category",
+	"sub_category",
+}
+
+// errInvalidImport はインポートファイル全体が不正な場合のエラーです
+var errInvalidImport = errors.New("invalid import file")
+
+// ImportRowError はインポートの行ごとのエラーです
+type ImportRowError struct {
+	Row    int      `json:"row"`
+	Word   string   `json:"word,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+// categoryIndex はカテゴリ名からカテゴリを引くための索引です
+type categoryIndex struct {
+	byID map[int]models.Category
+	main map[string]models.Category
+	sub  map[int]map[string]models.Category
+}
+
+// loadCategoryIndex は全カテゴリを読み込んで索引を作成します
+func loadCategoryIndex() (*categoryIndex, error) {
+	var categories []models.Category
+	if err := database.GetDB().Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	index := &categoryIndex{
+		byID: make(map[int]models.Category, len(categories)),
+		main: make(map[string]models.Category),
+		sub:  make(map[int]map[string]models.Category),
+	}
+	for _, category := range categories {
+		index.byID[category.ID] = category
+		key := strings.ToLower(category.Name)
+		if category.IsMain() {
+			index.main[key] = category
+			continue
+		}
+		if index.sub[*category.ParentID] == nil {
+			index.sub[*category.ParentID] = make(map[string]models.Category)
+		}
+		index.sub[*category.ParentID][key] = category
+	}
+	return index, nil
+}
+
+// name はカテゴリIDに対応する名前を返します
+func (idx *categoryIndex) name(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return idx.byID[*id].Name
+}
+
+// fileDelimiter はformatクエリパラメータまたはファイル名から区切り文字を決定します
+func fileDelimiter(format, filename string) (rune, bool) {
+	if format == "" {
+		format = "csv"
+		if strings.HasSuffix(strings.ToLower(filename), ".tsv") {
+			format = "tsv"
+		}
+	}
+	switch strings.ToLower(format) {
+	case "csv":
+		return ',', true
+	case "tsv":
+		return '\t', true
+	default:
+		return 0, false
+	}
+}
+
+// openImportFile はmultipartのfileフィールドまたはリクエストボディからファイルを開きます
+func openImportFile(c *gin.Context) (io.ReadCloser, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		return file, header.Filename, nil
+	}
+	return c.Request.Body, "", nil
+}
+
+// parseWordRows はファイルを読み込み、行番号付きの単語リクエストと行ごとのエラーを返します
+func parseWordRows(reader *csv.Reader, categories *categoryIndex) ([]*models.WordRequest, []int, []ImportRowError, error) {
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil, fmt.Errorf("%w: file is empty", errInvalidImport)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", errInvalidImport, err)
+	}
+
+	// 列名から列番号を取得（順不同、未知の列は無視）
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	if _, ok := columns[importColumnWord]; !ok {
+		return nil, nil, nil, fmt.Errorf("%w: header must contain a %q column", errInvalidImport, importColumnWord)
+	}
+
+	var (
+		requests  []*models.WordRequest
+		rowNums   []int
+		rowErrors []ImportRowError
+	)
+	seen := make(map[string]int)
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, ImportRowError{Row: row, Errors: []string{parseErr.Err.Error()}})
+				continue
+			}
+			return nil, nil, nil, fmt.Errorf("%w: %v", errInvalidImport, err)
+		}
+		if row-1 > maxImportRows {
+			return nil, nil, nil, fmt.Errorf("%w: file must contain at most %d rows", errInvalidImport, maxImportRows)
+		}
+
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return unescapeCell(strings.TrimSpace(record[i]))
+			}
+			return ""
+		}
+
+		req, errs := parseWordRow(field, categories)
+		if req.Word != "" {
+			key := strings.ToLower(req.Word)
+			if first, ok := seen[key]; ok {
+				errs = append(errs, fmt.Sprintf("duplicate of row %d", first))
+			} else {
+				seen[key] = row
+			}
+		}
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, ImportRowError{Row: row, Word: req.Word, Errors: errs})
+			continue
+		}
+		requests = append(requests, req)
+		rowNums = append(rowNums, row)
+	}
+
+	return requests, rowNums, rowErrors, nil
+}
+
+// formulaPrefixes は表計算ソフトが数式として解釈するセルの先頭文字です
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeCell は数式として解釈される値の先頭に'を付けます（CSVインジェクション対策）
+func escapeCell(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeCell はescapeCellで付けた'を取り除き、エクスポートしたファイルをそのままインポートできるようにします
+func unescapeCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// parseWordRow は1行分の値を単語リクエストに変換して検証します
+func parseWordRow(field func(string) string, categories *categoryIndex) (*models.WordRequest, []string) {
+	var errs []string
+	req := &models.WordRequest{Word: field(importColumnWord)}
+
+	optional := func(name string) *string {
+		if value := field(name); value != "" {
+			return &value
+		}
+		return nil
+	}
+	optionalInt := func(name string) *int {
+		value := field(name)
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s must be an integer", name))
+			return nil
+		}
+		return &n
+	}
+
+	req.JapaneseMeaning = optional("japanese_meaning")
+	req.PartOfSpeech = optional("part_of_speech")
+	req.Level = optionalInt("level")
+	req.DifficultyLevel = optionalInt("difficulty_level")
+
+	// カテゴリは名前で指定する
+	if name := field("main_category"); name != "" {
+		main, ok := categories.main[strings.ToLower(name)]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown main_category %q", name))
+		} else {
+			req.MainCategoryID = &main.ID
+			if subName := field("sub_category"); subName != "" {
+				sub, ok := categories.sub[main.ID][strings.ToLower(subName)]
+				if !ok {
+					errs = append(errs, fmt.Sprintf("unknown sub_category %q in %q", subName, name))
+				} else {
+					req.SubCategoryID = &sub.ID
+				}
+			}
+		}
+	} else if field("sub_category") != "" {
+		errs = append(errs, "sub_category requires main_category")
+	}
+
+	if err := validateWordRequest(req); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return req, errs
+}
+
+// importWords はファイルから単語を一括登録します。ownerIDがnilの場合はシステム単語として登録します
+// dry_run=trueの場合は検証のみ行い、エラーが1件でもあれば何も登録しません
+func importWords(c *gin.Context, ownerID *uint) {
+	dryRun := c.Query("dry_run") == "true"
+
+	file, filename, err := openImportFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+		return
+	}
+	defer file.Close()
+
+	delimiter, ok := fileDelimiter(c.Query("format"), filename)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Must be csv or tsv"})
+		return
+	}
+
+	categories, err := loadCategoryIndex()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	reader := csv.NewReader(file)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = delimiter == '\t'
+
+	requests, rowNums, rowErrors, err := parseWordRows(reader, categories)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	valid, imported := 0, 0
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 既存の単語との重複チェック
+		existing, err := existingWordKeys(tx, ownerID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		words := make([]models.Word, 0, len(requests))
+		for i, req := range requests {
+			if existing[strings.ToLower(req.Word)] {
+				rowErrors = append(rowErrors, ImportRowError{Row: rowNums[i], Word: req.Word, Errors: []string{"word already exists"}})
+				continue
+			}
+			words = append(words, models.Word{
+				Word:            req.Word,
+				JapaneseMeaning: req.JapaneseMeaning,
+				PartOfSpeech:    req.PartOfSpeech,
+				DifficultyLevel: req.DifficultyLevel,
+				IsSystem:        ownerID == nil,
+				OwnerID:         ownerID,
+				Level:           req.Level,
+				MainCategoryID:  req.MainCategoryID,
+				SubCategoryID:   req.SubCategoryID,
+				CreatedAt:       now,
+				UpdatedAt:       now,
+			})
+		}
+
+		valid = len(words)
+		if dryRun || len(rowErrors) > 0 || len(words) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(&words, importBatchSize).Error; err != nil {
+			return err
+		}
+		imported = len(words)
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import words"})
+		return
+	}
+
+	sortRowErrors(rowErrors)
+	status := http.StatusOK
+	message := "Words imported successfully"
+	switch {
+	case len(rowErrors) > 0:
+		status = http.StatusUnprocessableEntity
+		message = "Import has errors; no words were imported"
+	case dryRun:
+		message = "Dry run completed; no words were imported"
+	}
+
+	c.JSON(status, gin.H{
+		"message":  message,
+		"dry_run":  dryRun,
+		"valid":    valid,
+		"imported": imported,
+		"errors":   rowErrors,
+	})
+}
+
+// existingWordKeys は重複チェックの対象となる既存単語の綴り（小文字）を返します
+func existingWordKeys(tx *gorm.DB, ownerID *uint) (map[string]bool, error) {
+	query := tx.Model(&models.Word{})
+	if ownerID == nil {
+		query = query.Where("is_system = ?", true)
+	} else {
+		query = query.Scopes(visibleWords(*ownerID))
+	}
+
+	var words []string
+	if err := query.Pluck("LOWER(word)", &words).Error; err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(words))
+	for _, word := range words {
+		keys[word] = true
+	}
+	return keys, nil
+}
+
+// sortRowErrors は行エラーを行番号順に並べます
+func sortRowErrors(rowErrors []ImportRowError) {
+	sort.Slice(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Row < rowErrors[j].Row
+	})
+}
+
+// exportWords は単語をCSV/TSVでストリーミング出力します
+func exportWords(c *gin.Context, query *gorm.DB) {
+	format := c.DefaultQuery("format", "csv")
+	delimiter, ok := fileDelimiter(format, "")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Must be csv or tsv"})
+		return
+	}
+
+	categories, err := loadCategoryIndex()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if delimiter == '\t' {
+		contentType = "text/tab-separated-values; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="words.%s"`, strings.ToLower(format)))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Comma = delimiter
+	if err := writer.Write(wordFileColumns); err != nil {
+		return
+	}
+
+	formatInt := func(value *int) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.Itoa(*value)
+	}
+	formatString := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return escapeCell(*value)
+	}
+
+	// バッチごとに書き出してメモリ使用量を抑える
+	var batch []models.Word
+	result := query.Order("id").FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			w := &batch[i]
+			record := []string{
+				escapeCell(w.Word),
+				formatString(w.JapaneseMeaning),
+				formatString(w.PartOfSpeech),
+				formatInt(w.Level),
+				formatInt(w.DifficultyLevel),
+				escapeCell(categories.name(w.MainCategoryID)),
+				escapeCell(categories.name(w.SubCategoryID)),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		c.Writer.Flush()
+		return writer.Error()
+	})
+	if result.Error != nil {
+		// ヘッダー送信後のためステータスは変更できない
+		c.Error(result.Error)
+	}
+	writer.Flush()
+}
+
+// ImportWordsHandler は自分の単語のCSV/TSV一括インポートハンドラーです
+func ImportWordsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	importWords(c, &userID)
+}
+
+// ExportWordsHandler は閲覧可能な単語のCSV/TSVエクスポートハンドラーです
+// scopeにsystemまたはpersonalを指定して出力対象を絞り込めます
+func ExportWordsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query, ok := scopedWords(c, userID)
+	if !ok {
+		return
+	}
+
+	exportWords(c, query)
+}
@@ -6,6 +6,7 @@ import (
 	"backend/auth"
 	"backend/config"
 	"backend/database"
+	"backend/mail"
 	"backend/routes"
 )
 
@@ -13,8 +14,20 @@ func main() {
 	// 設定を読み込み
 	cfg := config.Load()
 
-	// JWT秘密鍵を初期化
-	auth.InitJWT(cfg.JWT.Secret)
+	// JWTの署名鍵とトークンの有効期間を初期化
+	if err := auth.InitJWT(cfg.JWT); err != nil {
+		log.Fatal("JWT initialization failed:", err)
+	}
+
+	// メール送信とアカウント管理を初期化
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatal("Mailer initialization failed:", err)
+	}
+	if err := auth.InitAuth(cfg.Auth, mailer, cfg.Mail.AppURL); err != nil {
+		log.Fatal("Auth initialization failed:", err)
+	}
+	auth.InitOIDC(cfg.OIDC, cfg.Auth.OIDCStateTTL)
 
 	// データベース接続
 	if err := database.Connect(cfg.Database); err != nil {
@@ -24,8 +37,16 @@ func main() {
 
 	log.Println("Database tables initialized")
 
+	// 最初の管理者を設定
+	if err := auth.SeedAdmin(cfg.Auth.AdminUsername); err != nil {
+		log.Printf("Warning: Failed to seed admin: %v", err)
+	}
+
 	// ルーターを設定
-	r := routes.SetupRouter(cfg)
+	r, err := routes.SetupRouter(cfg)
+	if err != nil {
+		log.Fatal("Router initialization failed:", err)
+	}
 
 	// サーバー起動
 	log.Printf("Server starting on port %s", cfg.Server.Port)
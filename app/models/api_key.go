@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey構造体 - スクリプトや外部連携用の個人APIキー（キー自体は保存せずハッシュのみ保存する）
+// Scopesはスペース区切りの権限（例: "words:read reviews:read"）
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID     uint       `json:"user_id" gorm:"column:user_id;not null;index"`
+	Name       string     `json:"name" gorm:"column:name;size:100;not null"`
+	Prefix     string     `json:"prefix" gorm:"column:prefix;size:16;not null"` // 一覧でキーを見分けるための先頭部分
+	KeyHash    string     `json:"-" gorm:"column:key_hash;size:64;not null;uniqueIndex"`
+	Scopes     string     `json:"scopes" gorm:"column:scopes;size:255;not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the APIKey model
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList はスコープを配列で返します
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// APIキー作成リクエスト構造体
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays *int     `json:"expires_in_days,omitempty"` // 省略時は90日
+}
+
+// APIKeyResponse APIキーレスポンス構造体（キー自体は含まない）
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ToResponse はAPIKeyをAPIKeyResponseに変換します
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// APIキー作成レスポンス構造体（Keyはこの時だけ返す）
+type CreateAPIKeyResponse struct {
+	Message string         `json:"message"`
+	Key     string         `json:"key"`
+	APIKey  APIKeyResponse `json:"api_key"`
+}
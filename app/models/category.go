@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Category構造体 - ParentIDがnilの場合はメインカテゴリ、それ以外はサブカテゴリ
+type Category struct {
+	ID          int       `json:"id" gorm:"primary_key;column:id"`
+	Name        string    `json:"name" gorm:"column:name;size:50;not null"`
+	Description *string   `json:"description" gorm:"column:description;type:text"`
+	ParentID    *int      `json:"parent_id" gorm:"column:parent_id;index"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the Category model
+func (Category) TableName() string {
+	return "categories"
+}
+
+// IsMain はメインカテゴリかどうかを返します
+func (c *Category) IsMain() bool {
+	return c.ParentID == nil
+}
+
+// CategoryRequest カテゴリ作成/更新リクエスト構造体
+type CategoryRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description,omitempty"`
+	ParentID    *int    `json:"parent_id,omitempty"`
+}
+
+// CategoryResponse カテゴリレスポンス構造体
+type CategoryResponse struct {
+	ID            int                `json:"id"`
+	Name          string             `json:"name"`
+	Description   *string            `json:"description"`
+	ParentID      *int               `json:"parent_id"`
+	SubCategories []CategoryResponse `json:"sub_categories,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// ToResponse はCategoryをCategoryResponseに変換します
+func (c *Category) ToResponse() CategoryResponse {
+	return CategoryResponse{
+		ID:          c.ID,
+		Name:        c.Name,
+		Description: c.Description,
+		ParentID:    c.ParentID,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Deck構造体 - ユーザーが作成する単語リスト
+type Deck struct {
+	ID          uint      `json:"id" gorm:"primary_key;column:id"`
+	UserID      uint      `json:"user_id" gorm:"column:user_id;not null;index"`
+	Name        string    `json:"name" gorm:"column:name;size:100;not null"`
+	Description *string   `json:"description" gorm:"column:description;type:text"`
+	StudyTarget *int      `json:"study_target" gorm:"column:study_target"` // 1日に新しく学習する単語数の目標
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the Deck model
+func (Deck) TableName() string {
+	return "decks"
+}
+
+// DeckWord構造体 - デッキに含まれる単語と並び順
+type DeckWord struct {
+	DeckID   uint      `json:"deck_id" gorm:"primary_key;column:deck_id;autoIncrement:false"`
+	WordID   uint      `json:"word_id" gorm:"primary_key;column:word_id;autoIncrement:false"`
+	Position int       `json:"position" gorm:"column:position;not null"`
+	AddedAt  time.Time `json:"added_at" gorm:"column:added_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the DeckWord model
+func (DeckWord) TableName() string {
+	return "deck_words"
+}
+
+// DeckRequest デッキ作成/更新リクエスト構造体
+type DeckRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description,omitempty"`
+	StudyTarget *int    `json:"study_target,omitempty"`
+}
+
+// DeckWordsRequest デッキへの単語追加/並び替えリクエスト構造体
+type DeckWordsRequest struct {
+	WordIDs []uint `json:"word_ids" binding:"required"`
+}
+
+// DeckResponse デッキレスポンス構造体
+type DeckResponse struct {
+	ID          uint           `json:"id"`
+	Name        string         `json:"name"`
+	Description *string        `json:"description"`
+	StudyTarget *int           `json:"study_target"`
+	WordCount   int64          `json:"word_count"`
+	Words       []WordResponse `json:"words,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// ToResponse はDeckをDeckResponseに変換します
+func (d *Deck) ToResponse() DeckResponse {
+	return DeckResponse{
+		ID:          d.ID,
+		Name:        d.Name,
+		Description: d.Description,
+		StudyTarget: d.StudyTarget,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// EmailVerificationToken構造体 - メールアドレス確認トークン（トークン自体は保存せずハッシュのみ保存する）
+// Emailは確認対象のメールアドレスで、確認時にユーザーのメールアドレスとして設定される
+type EmailVerificationToken struct {
+	ID        uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID    uint       `json:"user_id" gorm:"column:user_id;not null;index"`
+	Email     string     `json:"email" gorm:"column:email;size:100;not null"`
+	TokenHash string     `json:"-" gorm:"column:token_hash;size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the EmailVerificationToken model
+func (EmailVerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
+
+// VerifyEmailRequest メールアドレス確認リクエスト構造体
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
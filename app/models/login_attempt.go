@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LoginAttempt構造体 - ユーザー名・IPアドレスごとのログイン失敗回数
+// Keyは "user:<ユーザー名>" または "ip:<IPアドレス>" の形式
+type LoginAttempt struct {
+	Key           string     `json:"key" gorm:"primary_key;column:key;size:320"`
+	Failures      int        `json:"failures" gorm:"column:failures;not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"column:last_failure_at;not null;index"`
+	LockedUntil   *time.Time `json:"locked_until" gorm:"column:locked_until"`
+}
+
+// TableName specifies the table name for the LoginAttempt model
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
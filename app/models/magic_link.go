@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// MagicLinkToken構造体 - パスワードなしでログインするためのリンクのトークン（トークン自体は保存せずハッシュのみ保存する）
+// UserIDがnilのトークンは未登録のメールアドレス宛てで、アカウントの自動作成が有効な場合のみ発行される
+type MagicLinkToken struct {
+	ID        uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID    *uint      `json:"user_id" gorm:"column:user_id;index"`
+	Email     string     `json:"email" gorm:"column:email;size:100;not null;index"`
+	TokenHash string     `json:"-" gorm:"column:token_hash;size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the MagicLinkToken model
+func (MagicLinkToken) TableName() string {
+	return "magic_link_tokens"
+}
+
+// MagicLinkRequest ログインリンク申請リクエスト構造体
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ConsumeMagicLinkRequest ログインリンクでのログインリクエスト構造体
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// TOTPCredential構造体 - ユーザーの認証アプリ（TOTP）の登録
+// ConfirmedAtがnilの間は登録途中で、ログインには使用しない
+// SecretはMFA_SECRET_KEYを設定した場合はAES-GCMで暗号化して保存する（"enc:v1:"で始まる）
+type TOTPCredential struct {
+	ID           uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID       uint       `json:"user_id" gorm:"column:user_id;not null;uniqueIndex"`
+	Secret       string     `json:"-" gorm:"column:secret;size:255;not null"`
+	ConfirmedAt  *time.Time `json:"confirmed_at" gorm:"column:confirmed_at"`
+	LastUsedStep int64      `json:"-" gorm:"column:last_used_step;not null;default:0"` // 同じコードの再利用を防ぐ
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the TOTPCredential model
+func (TOTPCredential) TableName() string {
+	return "totp_credentials"
+}
+
+// RecoveryCode構造体 - 認証アプリを使えない場合のリカバリーコード（ハッシュのみ保存、1回限り）
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID    uint       `json:"user_id" gorm:"column:user_id;not null;index"`
+	CodeHash  string     `json:"-" gorm:"column:code_hash;size:64;not null"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the RecoveryCode model
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// MFAChallenge構造体 - パスワード認証後、2要素目の入力を待っているログイン（トークンはハッシュのみ保存）
+type MFAChallenge struct {
+	ID        uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID    uint       `json:"user_id" gorm:"column:user_id;not null;index"`
+	TokenHash string     `json:"-" gorm:"column:token_hash;size:64;not null;uniqueIndex"`
+	Attempts  int        `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"column:expires_at;not null;index"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the MFAChallenge model
+func (MFAChallenge) TableName() string {
+	return "mfa_challenges"
+}
+
+// TOTP登録開始リクエスト構造体
+type EnrollTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// TOTP登録レスポンス構造体
+// SecretとURIは認証アプリへの登録に使用し、この時だけ返す
+type EnrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TOTP登録確認リクエスト構造体
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// 2要素認証の無効化・リカバリーコード再発行リクエスト構造体
+type MFAPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTPコードまたはリカバリーコード
+}
+
+// リカバリーコードレスポンス構造体（コードはこの時だけ返す）
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// 2要素認証ログインリクエスト構造体
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTPコードまたはリカバリーコード
+}
+
+// 2要素認証が必要な場合のログインレスポンス構造体
+// MFATokenとコードを /auth/login/mfa に送るとAuthResponseを返す
+type MFARequiredResponse struct {
+	Message     string `json:"message"`
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"` // MFATokenの有効期間（秒）
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// OIDCIdentity構造体 - 外部のOpenID Connectプロバイダー（Google、Appleなど）のアカウントとユーザーの紐付け
+type OIDCIdentity struct {
+	ID          uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID      uint       `json:"user_id" gorm:"column:user_id;not null;index"`
+	Provider    string     `json:"provider" gorm:"column:provider;size:50;not null;uniqueIndex:idx_oidc_identities_provider_subject"`
+	Subject     string     `json:"-" gorm:"column:subject;size:255;not null;uniqueIndex:idx_oidc_identities_provider_subject"` // IDトークンのsub
+	Email       string     `json:"email" gorm:"column:email;size:100"`                                                         // 最後のログイン時のメールアドレス
+	LastLoginAt *time.Time `json:"last_login_at" gorm:"column:last_login_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the OIDCIdentity model
+func (OIDCIdentity) TableName() string {
+	return "oidc_identities"
+}
+
+// OIDCLoginState構造体 - 開始した認可リクエスト（stateはハッシュのみ保存、コールバックで1回限り使用）
+type OIDCLoginState struct {
+	ID           uint      `json:"id" gorm:"primary_key;column:id"`
+	Provider     string    `json:"provider" gorm:"column:provider;size:50;not null"`
+	StateHash    string    `json:"-" gorm:"column:state_hash;size:64;not null;uniqueIndex"`
+	Nonce        string    `json:"-" gorm:"column:nonce;size:64;not null"`
+	CodeVerifier string    `json:"-" gorm:"column:code_verifier;size:128;not null"` // PKCE
+	ExpiresAt    time.Time `json:"expires_at" gorm:"column:expires_at;not null;index"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the OIDCLoginState model
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
+
+// OIDCStartResponse 外部プロバイダーでのログイン開始レスポンス構造体
+// フロントエンドはstateを保存してからauthorization_urlに遷移し、コールバックで受け取ったstateと照合します
+type OIDCStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int64  `json:"expires_in"`
+}
+
+// OIDCCallbackRequest 外部プロバイダーからのコールバックリクエスト構造体
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
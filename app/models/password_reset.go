@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// PasswordResetToken構造体 - パスワードリセットトークン（トークン自体は保存せずハッシュのみ保存する）
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID    uint       `json:"user_id" gorm:"column:user_id;not null;index"`
+	TokenHash string     `json:"-" gorm:"column:token_hash;size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `json:"used_at" gorm:"column:used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the PasswordResetToken model
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// ForgotPasswordRequest パスワードリセット申請リクエスト構造体
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest パスワードリセットリクエスト構造体
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"backend/srs"
+)
+
+// WordProgress構造体 - ユーザーごとの単語の学習状態
+type WordProgress struct {
+	ID             uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID         uint       `json:"user_id" gorm:"column:user_id;not null;uniqueIndex:idx_word_progress_user_word"`
+	WordID         uint       `json:"word_id" gorm:"column:word_id;not null;uniqueIndex:idx_word_progress_user_word"`
+	Algorithm      string     `json:"algorithm" gorm:"column:algorithm;size:20;not null;default:'SM2'"`
+	EaseFactor     float64    `json:"ease_factor" gorm:"column:ease_factor;not null;default:2.5"`
+	Box            int        `json:"box" gorm:"column:box;not null;default:1"`
+	Stability      float64    `json:"stability" gorm:"column:stability;not null;default:0"`
+	Difficulty     float64    `json:"difficulty" gorm:"column:difficulty;not null;default:0"`
+	IntervalDays   int        `json:"interval_days" gorm:"column:interval_days;not null;default:0"`
+	Repetitions    int        `json:"repetitions" gorm:"column:repetitions;not null;default:0"`
+	DueAt          time.Time  `json:"due_at" gorm:"column:due_at;not null;index"`
+	LastGrade      *int       `json:"last_grade" gorm:"column:last_grade"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at" gorm:"column:last_reviewed_at"`
+	ReviewCount    int        `json:"review_count" gorm:"column:review_count;not null;default:0"`
+	LapseCount     int        `json:"lapse_count" gorm:"column:lapse_count;not null;default:0"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the WordProgress model
+func (WordProgress) TableName() string {
+	return "word_progress"
+}
+
+// ReviewRequest 復習結果送信リクエスト構造体
+type ReviewRequest struct {
+	Grade *int `json:"grade" binding:"required"`
+}
+
+// WordProgressResponse 学習状態レスポンス構造体
+type WordProgressResponse struct {
+	WordID         uint          `json:"word_id"`
+	Algorithm      string        `json:"algorithm"`
+	EaseFactor     float64       `json:"ease_factor"`
+	Box            int           `json:"box"`
+	Stability      float64       `json:"stability"`
+	Difficulty     float64       `json:"difficulty"`
+	IntervalDays   int           `json:"interval_days"`
+	Repetitions    int           `json:"repetitions"`
+	DueAt          time.Time     `json:"due_at"`
+	LastGrade      *int          `json:"last_grade"`
+	LastReviewedAt *time.Time    `json:"last_reviewed_at"`
+	ReviewCount    int           `json:"review_count"`
+	LapseCount     int           `json:"lapse_count"`
+	Word           *WordResponse `json:"word,omitempty"`
+}
+
+// ToResponse はWordProgressをWordProgressResponseに変換します
+func (p *WordProgress) ToResponse() WordProgressResponse {
+	return WordProgressResponse{
+		WordID:         p.WordID,
+		Algorithm:      p.Algorithm,
+		EaseFactor:     p.EaseFactor,
+		Box:            p.Box,
+		Stability:      p.Stability,
+		Difficulty:     p.Difficulty,
+		IntervalDays:   p.IntervalDays,
+		Repetitions:    p.Repetitions,
+		DueAt:          p.DueAt,
+		LastGrade:      p.LastGrade,
+		LastReviewedAt: p.LastReviewedAt,
+		ReviewCount:    p.ReviewCount,
+		LapseCount:     p.LapseCount,
+	}
+}
+
+// Card はWordProgressをスケジューラーのカード状態に変換します
+func (p *WordProgress) Card() srs.Card {
+	return srs.Card{
+		Algorithm:      p.Algorithm,
+		IntervalDays:   p.IntervalDays,
+		Repetitions:    p.Repetitions,
+		Lapses:         p.LapseCount,
+		LastReviewedAt: p.LastReviewedAt,
+		EaseFactor:     p.EaseFactor,
+		Box:            p.Box,
+		Stability:      p.Stability,
+		Difficulty:     p.Difficulty,
+	}
+}
+
+// ApplyCard はスケジューラーのカード状態をWordProgressに反映します
+func (p *WordProgress) ApplyCard(card srs.Card) {
+	p.Algorithm = card.Algorithm
+	p.IntervalDays = card.IntervalDays
+	p.Repetitions = card.Repetitions
+	p.LapseCount = card.Lapses
+	p.LastReviewedAt = card.LastReviewedAt
+	p.EaseFactor = card.EaseFactor
+	p.Box = card.Box
+	p.Stability = card.Stability
+	p.Difficulty = card.Difficulty
+}
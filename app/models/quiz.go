@@ -0,0 +1,142 @@
+package models
+
+import "time"
+
+// クイズの出題方向
+const (
+	QuizDirectionEnJa  = "EN_JA"
+	QuizDirectionJaEn  = "JA_EN"
+	QuizDirectionMixed = "MIXED"
+)
+
+// クイズセッションの状態
+const (
+	QuizStatusInProgress = "IN_PROGRESS"
+	QuizStatusCompleted  = "COMPLETED"
+)
+
+// QuizSession構造体 - ユーザーごとのクイズセッションと結果
+type QuizSession struct {
+	ID            uint           `json:"id" gorm:"primary_key;column:id"`
+	UserID        uint           `json:"user_id" gorm:"column:user_id;not null;index"`
+	DeckID        *uint          `json:"deck_id" gorm:"column:deck_id"` // デッキから出題した場合のみ
+	Direction     string         `json:"direction" gorm:"column:direction;size:10;not null;check:direction in ('EN_JA', 'JA_EN', 'MIXED')"`
+	Status        string         `json:"status" gorm:"column:status;size:20;not null;default:'IN_PROGRESS';check:status in ('IN_PROGRESS', 'COMPLETED')"`
+	QuestionCount int            `json:"question_count" gorm:"column:question_count;not null"`
+	CorrectCount  int            `json:"correct_count" gorm:"column:correct_count;not null;default:0"`
+	Score         *float64       `json:"score" gorm:"column:score"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	CompletedAt   *time.Time     `json:"completed_at" gorm:"column:completed_at"`
+	Questions     []QuizQuestion `json:"-" gorm:"foreignKey:SessionID"`
+}
+
+// TableName specifies the table name for the QuizSession model
+func (QuizSession) TableName() string {
+	return "quiz_sessions"
+}
+
+// QuizQuestion構造体 - 正解はサーバー側にのみ保持する
+type QuizQuestion struct {
+	ID            uint       `json:"id" gorm:"primary_key;column:id"`
+	SessionID     uint       `json:"session_id" gorm:"column:session_id;not null;index"`
+	Position      int        `json:"position" gorm:"column:position;not null"`
+	WordID        uint       `json:"word_id" gorm:"column:word_id;not null"`
+	Direction     string     `json:"direction" gorm:"column:direction;size:10;not null"`
+	Prompt        string     `json:"prompt" gorm:"column:prompt;type:text;not null"`
+	Options       []string   `json:"options" gorm:"column:options;type:jsonb;serializer:json;not null"`
+	CorrectIndex  int        `json:"-" gorm:"column:correct_index;not null"` // クライアントには返さない
+	AnsweredIndex *int       `json:"answered_index" gorm:"column:answered_index"`
+	IsCorrect     *bool      `json:"is_correct" gorm:"column:is_correct"`
+	AnsweredAt    *time.Time `json:"answered_at" gorm:"column:answered_at"`
+}
+
+// TableName specifies the table name for the QuizQuestion model
+func (QuizQuestion) TableName() string {
+	return "quiz_questions"
+}
+
+// CreateQuizRequest クイズ作成リクエスト構造体
+type CreateQuizRequest struct {
+	QuestionCount  int     `json:"question_count,omitempty"`
+	Direction      string  `json:"direction,omitempty"`
+	Level          *int    `json:"level,omitempty"`
+	MainCategoryID *int    `json:"main_category_id,omitempty"`
+	PartOfSpeech   *string `json:"part_of_speech,omitempty"`
+}
+
+// QuizAnswerRequest クイズ回答リクエスト構造体
+type QuizAnswerRequest struct {
+	QuestionID  uint `json:"question_id" binding:"required"`
+	AnswerIndex *int `json:"answer_index" binding:"required"`
+}
+
+// QuizQuestionResponse 問題レスポンス構造体
+// CorrectIndexは回答済みまたはセッション完了後のみ設定される
+type QuizQuestionResponse struct {
+	ID            uint     `json:"id"`
+	Position      int      `json:"position"`
+	Direction     string   `json:"direction"`
+	Prompt        string   `json:"prompt"`
+	Options       []string `json:"options"`
+	AnsweredIndex *int     `json:"answered_index"`
+	IsCorrect     *bool    `json:"is_correct"`
+	CorrectIndex  *int     `json:"correct_index,omitempty"`
+}
+
+// QuizSessionResponse クイズセッションレスポンス構造体
+type QuizSessionResponse struct {
+	ID            uint                   `json:"id"`
+	DeckID        *uint                  `json:"deck_id"`
+	Direction     string                 `json:"direction"`
+	Status        string                 `json:"status"`
+	QuestionCount int                    `json:"question_count"`
+	AnsweredCount int                    `json:"answered_count"`
+	CorrectCount  int                    `json:"correct_count"`
+	Score         *float64               `json:"score"`
+	CreatedAt     time.Time              `json:"created_at"`
+	CompletedAt   *time.Time             `json:"completed_at"`
+	Questions     []QuizQuestionResponse `json:"questions,omitempty"`
+}
+
+// ToResponse はQuizQuestionをQuizQuestionResponseに変換します
+// 未回答かつセッション未完了の場合は正解を含めません
+func (q *QuizQuestion) ToResponse(revealAnswer bool) QuizQuestionResponse {
+	response := QuizQuestionResponse{
+		ID:            q.ID,
+		Position:      q.Position,
+		Direction:     q.Direction,
+		Prompt:        q.Prompt,
+		Options:       q.Options,
+		AnsweredIndex: q.AnsweredIndex,
+		IsCorrect:     q.IsCorrect,
+	}
+	if revealAnswer || q.AnsweredIndex != nil {
+		correctIndex := q.CorrectIndex
+		response.CorrectIndex = &correctIndex
+	}
+	return response
+}
+
+// ToResponse はQuizSessionをQuizSessionResponseに変換します
+func (s *QuizSession) ToResponse() QuizSessionResponse {
+	response := QuizSessionResponse{
+		ID:            s.ID,
+		DeckID:        s.DeckID,
+		Direction:     s.Direction,
+		Status:        s.Status,
+		QuestionCount: s.QuestionCount,
+		CorrectCount:  s.CorrectCount,
+		Score:         s.Score,
+		CreatedAt:     s.CreatedAt,
+		CompletedAt:   s.CompletedAt,
+	}
+
+	completed := s.Status == QuizStatusCompleted
+	for i := range s.Questions {
+		if s.Questions[i].AnsweredIndex != nil {
+			response.AnsweredCount++
+		}
+		response.Questions = append(response.Questions, s.Questions[i].ToResponse(completed))
+	}
+	return response
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// RefreshToken構造体 - リフレッシュトークン（トークン自体は保存せずハッシュのみ保存する）
+// 同じログイン（端末）から続けてローテーションされたトークンは同じFamilyIDを持つ
+type RefreshToken struct {
+	ID           uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID       uint       `json:"user_id" gorm:"column:user_id;not null;index"`
+	FamilyID     string     `json:"family_id" gorm:"column:family_id;size:64;not null;index"`
+	TokenHash    string     `json:"-" gorm:"column:token_hash;size:64;not null;uniqueIndex"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	UsedAt       *time.Time `json:"used_at" gorm:"column:used_at"`               // ローテーション済みの場合のみ
+	ReplacedByID *uint      `json:"replaced_by_id" gorm:"column:replaced_by_id"` // ローテーション後のトークン
+	RevokedAt    *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the RefreshToken model
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RefreshRequest トークン再発行リクエスト構造体
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest ログアウトリクエスト構造体
+// RefreshTokenを指定するとその端末のリフレッシュトークンも失効させ、AllDevicesを指定すると全端末からログアウトする
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+	AllDevices   bool   `json:"all_devices,omitempty"`
+}
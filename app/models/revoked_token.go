@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RevokedToken構造体 - ログアウトなどで失効させたアクセストークン（jti）
+// ExpiresAtを過ぎたトークンは検証で拒否されるため、行を削除してよい
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primary_key;column:jti;size:64"`
+	UserID    uint      `json:"user_id" gorm:"column:user_id;not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;not null;index"`
+	RevokedAt time.Time `json:"revoked_at" gorm:"column:revoked_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TableName specifies the table name for the RevokedToken model
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
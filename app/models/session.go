@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Session構造体 - ログインした端末ごとのセッション
+// 端末のリフレッシュトークンのファミリーと1対1で対応する
+type Session struct {
+	ID         uint       `json:"id" gorm:"primary_key;column:id"`
+	UserID     uint       `json:"user_id" gorm:"column:user_id;not null;index"`
+	FamilyID   string     `json:"-" gorm:"column:family_id;size:64;not null;uniqueIndex"`
+	UserAgent  string     `json:"user_agent" gorm:"column:user_agent;size:255;not null;default:''"`
+	IPAddress  string     `json:"ip_address" gorm:"column:ip_address;size:45;not null;default:''"` // X-Forwarded-ForはTRUSTED_PROXIESのプロキシからの場合のみ使う
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"column:last_seen_at;not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"column:expires_at;not null"` // リフレッシュトークンの有効期限
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+}
+
+// TableName specifies the table name for the Session model
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// SessionResponse セッションレスポンス構造体
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // リクエストに使用したセッションかどうか
+}
+
+// ToResponse はSessionをSessionResponseに変換します
+func (s *Session) ToResponse(currentID uint) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    s.ID == currentID,
+	}
+}
@@ -2,16 +2,28 @@ package models
 
 import "time"
 
+// ユーザーの役割（admin > editor > user の順に権限が強い）
+const (
+	RoleUser   = "user"   // 自分の単語・デッキのみ管理できる
+	RoleEditor = "editor" // システム単語とカテゴリを管理できる
+	RoleAdmin  = "admin"  // ユーザーも管理できる
+)
+
 // User構造体
 type User struct {
-	ID              uint       `json:"id" gorm:"primary_key;column:user_id"`
-	Username        string     `json:"username" gorm:"not null;unique;size:50"`
-	Email           string     `json:"email" gorm:"not null;unique;size:100"`
-	PasswordHash    string     `json:"-" gorm:"not null;size:255;column:password_hash"` // JSONには含めない
-	PreferredAccent string     `json:"preferred_accent" gorm:"default:'US';size:10;check:preferred_accent in ('US', 'UK')"`
-	StudyLevel      string     `json:"study_level" gorm:"default:'BEGINNER';size:20;check:study_level in ('BEGINNER', 'INTERMEDIATE', 'ADVANCED')"`
-	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
-	LastLogin       *time.Time `json:"last_login" gorm:"column:last_login"`
+	ID                  uint       `json:"id" gorm:"primary_key;column:user_id"`
+	Username            string     `json:"username" gorm:"not null;unique;size:50"`
+	Email               string     `json:"email" gorm:"not null;unique;size:100"`
+	PasswordHash        string     `json:"-" gorm:"not null;size:255;column:password_hash"` // JSONには含めない
+	PreferredAccent     string     `json:"preferred_accent" gorm:"default:'US';size:10;check:preferred_accent in ('US', 'UK')"`
+	StudyLevel          string     `json:"study_level" gorm:"default:'BEGINNER';size:20;check:study_level in ('BEGINNER', 'INTERMEDIATE', 'ADVANCED')"`
+	SchedulingAlgorithm string     `json:"scheduling_algorithm" gorm:"default:'SM2';size:20;not null;check:scheduling_algorithm in ('SM2', 'LEITNER', 'FSRS')"`
+	CreatedAt           time.Time  `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP"`
+	LastLogin           *time.Time `json:"last_login" gorm:"column:last_login"`
+	TokenVersion        int        `json:"-" gorm:"column:token_version;not null;default:0"` // 全端末ログアウトで増加する
+	EmailVerifiedAt     *time.Time `json:"email_verified_at" gorm:"column:email_verified_at"`
+	MFAEnabled          bool       `json:"mfa_enabled" gorm:"column:mfa_enabled;not null;default:false"` // 確認済みのTOTPがある
+	Role                string     `json:"role" gorm:"default:'user';size:20;not null;check:role in ('user', 'editor', 'admin')"`
 }
 
 // TableName specifies the table name for the User model
@@ -34,33 +46,61 @@ type RegisterRequest struct {
 	StudyLevel      string `json:"study_level,omitempty"`
 }
 
+// パスワード変更リクエスト構造体
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// メールアドレス変更リクエスト構造体
+type ChangeEmailRequest struct {
+	Email           string `json:"email" binding:"required,email"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// 役割変更リクエスト構造体（管理者用）
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
 // ユーザーレスポンス構造体（パスワードを除外）
 type UserResponse struct {
-	ID              uint       `json:"id"`
-	Username        string     `json:"username"`
-	Email           string     `json:"email"`
-	PreferredAccent string     `json:"preferred_accent"`
-	StudyLevel      string     `json:"study_level"`
-	CreatedAt       time.Time  `json:"created_at"`
-	LastLogin       *time.Time `json:"last_login,omitempty"`
+	ID                  uint       `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	PreferredAccent     string     `json:"preferred_accent"`
+	StudyLevel          string     `json:"study_level"`
+	SchedulingAlgorithm string     `json:"scheduling_algorithm"`
+	CreatedAt           time.Time  `json:"created_at"`
+	LastLogin           *time.Time `json:"last_login,omitempty"`
+	EmailVerified       bool       `json:"email_verified"`
+	MFAEnabled          bool       `json:"mfa_enabled"`
+	Role                string     `json:"role"`
 }
 
 // ログイン/登録レスポンス構造体
+// Tokenは短命なアクセストークン、RefreshTokenはアクセストークンの再発行に使う不透明なトークン
 type AuthResponse struct {
-	Message string       `json:"message"`
-	Token   string       `json:"token"`
-	User    UserResponse `json:"user"`
+	Message      string       `json:"message"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int64        `json:"expires_in"` // アクセストークンの有効期間（秒）
+	User         UserResponse `json:"user"`
 }
 
 // ToResponse はUserをUserResponseに変換します
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:              u.ID,
-		Username:        u.Username,
-		Email:           u.Email,
-		PreferredAccent: u.PreferredAccent,
-		StudyLevel:      u.StudyLevel,
-		CreatedAt:       u.CreatedAt,
-		LastLogin:       u.LastLogin,
+		ID:                  u.ID,
+		Username:            u.Username,
+		Email:               u.Email,
+		PreferredAccent:     u.PreferredAccent,
+		StudyLevel:          u.StudyLevel,
+		SchedulingAlgorithm: u.SchedulingAlgorithm,
+		CreatedAt:           u.CreatedAt,
+		LastLogin:           u.LastLogin,
+		EmailVerified:       u.EmailVerifiedAt != nil,
+		MFAEnabled:          u.MFAEnabled,
+		Role:                u.Role,
 	}
 }
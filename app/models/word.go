@@ -2,16 +2,57 @@ package models
 
 import "time"
 
+// 単語レベルの範囲（CEFR A1〜C2に対応）
+const (
+	MinWordLevel = 1
+	MaxWordLevel = 6
+)
+
+// 難易度の範囲（words_difficulty_level_check制約と一致させる）
+const (
+	MinDifficultyLevel = 1
+	MaxDifficultyLevel = 3
+)
+
+// PartsOfSpeech は許可される品詞の一覧です
+var PartsOfSpeech = []string{
+	"noun",
+	"verb",
+	"adjective",
+	"adverb",
+	"pronoun",
+	"preposition",
+	"conjunction",
+	"interjection",
+	"auxiliary",
+	"determiner",
+	"phrase",
+}
+
+// IsValidPartOfSpeech は品詞が許可された値かを判定します
+func IsValidPartOfSpeech(pos string) bool {
+	for _, p := range PartsOfSpeech {
+		if p == pos {
+			return true
+		}
+	}
+	return false
+}
+
 // Word構造体 - 冗長なフィールドを削除
 type Word struct {
-	ID             uint      `json:"id" gorm:"primary_key;column:id"`
-	Word           string    `json:"word" gorm:"column:word;size:100;not null"`
-	IsSystem       bool      `json:"is_system" gorm:"column:is_system;default:true;not null"`
-	Level          *int      `json:"level" gorm:"column:level"`
-	MainCategoryID *int      `json:"main_category_id" gorm:"column:main_category_id"`
-	SubCategoryID  *int      `json:"sub_category_id" gorm:"column:sub_category_id"`
-	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
-	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+	ID              uint      `json:"id" gorm:"primary_key;column:id"`
+	Word            string    `json:"word" gorm:"column:word;size:100;not null"`
+	JapaneseMeaning *string   `json:"japanese_meaning" gorm:"column:japanese_meaning;type:text"`
+	PartOfSpeech    *string   `json:"part_of_speech" gorm:"column:part_of_speech;size:20"`
+	DifficultyLevel *int      `json:"difficulty_level" gorm:"column:difficulty_level;type:integer"`
+	IsSystem        bool      `json:"is_system" gorm:"column:is_system;default:false;not null"`
+	OwnerID         *uint     `json:"owner_id" gorm:"column:owner_id;index"` // システム単語の場合はnil
+	Level           *int      `json:"level" gorm:"column:level"`
+	MainCategoryID  *int      `json:"main_category_id" gorm:"column:main_category_id"`
+	SubCategoryID   *int      `json:"sub_category_id" gorm:"column:sub_category_id"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
 }
 
 // TableName specifies the table name for the Word model
@@ -21,35 +62,46 @@ func (Word) TableName() string {
 
 // WordRequest 単語作成/更新リクエスト構造体
 type WordRequest struct {
-	Word           string `json:"word" binding:"required"`
-	IsSystem       *bool  `json:"is_system,omitempty"`
-	Level          *int   `json:"level,omitempty"`
-	MainCategoryID *int   `json:"main_category_id,omitempty"`
-	SubCategoryID  *int   `json:"sub_category_id,omitempty"`
+	Word            string  `json:"word" binding:"required"`
+	JapaneseMeaning *string `json:"japanese_meaning,omitempty"`
+	PartOfSpeech    *string `json:"part_of_speech,omitempty"`
+	DifficultyLevel *int    `json:"difficulty_level,omitempty"`
+	IsSystem        *bool   `json:"is_system,omitempty"`
+	Level           *int    `json:"level,omitempty"`
+	MainCategoryID  *int    `json:"main_category_id,omitempty"`
+	SubCategoryID   *int    `json:"sub_category_id,omitempty"`
 }
 
 // WordResponse 単語レスポンス構造体
 type WordResponse struct {
-	ID             uint      `json:"id"`
-	Word           string    `json:"word"`
-	IsSystem       bool      `json:"is_system"`
-	Level          *int      `json:"level"`
-	MainCategoryID *int      `json:"main_category_id"`
-	SubCategoryID  *int      `json:"sub_category_id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID              uint      `json:"id"`
+	Word            string    `json:"word"`
+	JapaneseMeaning *string   `json:"japanese_meaning"`
+	PartOfSpeech    *string   `json:"part_of_speech"`
+	DifficultyLevel *int      `json:"difficulty_level"`
+	IsSystem        bool      `json:"is_system"`
+	OwnerID         *uint     `json:"owner_id"`
+	Level           *int      `json:"level"`
+	MainCategoryID  *int      `json:"main_category_id"`
+	SubCategoryID   *int      `json:"sub_category_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 // ToResponse はWordをWordResponseに変換します
 func (w *Word) ToResponse() WordResponse {
 	return WordResponse{
-		ID:             w.ID,
-		Word:           w.Word,
-		IsSystem:       w.IsSystem,
-		Level:          w.Level,
-		MainCategoryID: w.MainCategoryID,
-		SubCategoryID:  w.SubCategoryID,
-		CreatedAt:      w.CreatedAt,
-		UpdatedAt:      w.UpdatedAt,
+		ID:              w.ID,
+		Word:            w.Word,
+		JapaneseMeaning: w.JapaneseMeaning,
+		PartOfSpeech:    w.PartOfSpeech,
+		DifficultyLevel: w.DifficultyLevel,
+		IsSystem:        w.IsSystem,
+		OwnerID:         w.OwnerID,
+		Level:           w.Level,
+		MainCategoryID:  w.MainCategoryID,
+		SubCategoryID:   w.SubCategoryID,
+		CreatedAt:       w.CreatedAt,
+		UpdatedAt:       w.UpdatedAt,
 	}
 }
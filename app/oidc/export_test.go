@@ -0,0 +1,8 @@
+package oidc
+
+// ExpireKeys はJWKSを取得した時刻を再取得の最小間隔より前にします（テストで鍵の再取得を許可する）
+func (p *Provider) ExpireKeys() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keysFetchedAt = p.keysFetchedAt.Add(-jwksRefreshMinWait)
+}
@@ -0,0 +1,442 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// クライアントのパラメータ
+const (
+	httpTimeout        = 10 * time.Second
+	maxResponseSize    = 1 << 20          // プロバイダーのレスポンスの最大サイズ
+	jwksRefreshMinWait = time.Minute      // 未知のkidで鍵を再取得する最小間隔
+	idTokenLeeway      = 60 * time.Second // IDトークンのexp・iatの検証で許容する時計のずれ
+)
+
+// idTokenMethods はIDトークンの署名で受け付けるアルゴリズムです（noneやHMACは受け付けない）
+var idTokenMethods = []string{"RS256", "RS384", "RS512", "ES256", "EdDSA"}
+
+var (
+	// ErrTokenRejected はトークンエンドポイントが認可コードを拒否した場合のエラーです
+	ErrTokenRejected = errors.New("authorization code rejected")
+	// ErrInvalidIDToken はIDトークンの署名・クレームが不正な場合のエラーです
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Discovery はプロバイダーの設定情報（/.well-known/openid-configuration）です
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims はIDトークンのクレームです
+type Claims struct {
+	Email           string `json:"email"`
+	EmailVerified   Bool   `json:"email_verified"`
+	Name            string `json:"name"`
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// Bool はJSONの真偽値です（Appleのように"true"の文字列で返すプロバイダーにも対応する）
+type Bool bool
+
+// UnmarshalJSON は真偽値または"true"/"false"の文字列を読み込みます
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean: %s", data)
+	}
+	return nil
+}
+
+// publicKey はJWKSから読み込んだ検証鍵です
+type publicKey struct {
+	alg string // 空の場合は鍵の種類に合うアルゴリズムを受け付ける
+	key crypto.PublicKey
+}
+
+// Provider はOpenID Connectプロバイダーの認可コードフロー（PKCE）のクライアントです
+// 設定情報と公開鍵はプロバイダーから取得してキャッシュします
+type Provider struct {
+	cfg    config.OIDCProviderConfig
+	client *http.Client
+
+	// muはキャッシュの読み書きの間だけ保持し、プロバイダーへのリクエスト中は保持しない
+	mu            sync.Mutex
+	discovery     *Discovery
+	keys          map[string]publicKey
+	keysFetchedAt time.Time
+	keysFetching  chan struct{} // JWKSの取得中の場合、取得が終わると閉じられる
+}
+
+// NewProvider は設定からProviderを作成します（clientがnilの場合は既定のHTTPクライアントを使用）
+func NewProvider(cfg config.OIDCProviderConfig, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: httpTimeout}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+// Name はプロバイダー名を返します
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// NewVerifier はPKCEのcode_verifierを生成します（RFC 7636、43文字）
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge はcode_verifierからS256のcode_challengeを求めます
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL はユーザーをリダイレクトする認可エンドポイントのURLを返します
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", Challenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange は認可コードをトークンエンドポイントでIDトークンと交換し、検証したクレームを返します
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic（RFC 6749 2.3.1により値はURLエンコードする）
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid token response (status %d): %w", resp.StatusCode, err)
+	}
+	if body.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrTokenRejected, body.Error, body.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+
+	return p.VerifyIDToken(ctx, body.IDToken, nonce)
+}
+
+// VerifyIDToken はIDトークンの署名をプロバイダーのJWKSで検証し、iss・aud・exp・nonceを確認します
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		return p.keyFor(ctx, discovery.JWKSURI, token)
+	},
+		jwt.WithValidMethods(idTokenMethods),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	// 認可リクエストのnonceと一致しないトークンは、別のログインから流用されたものとみなす
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if claims.AuthorizedParty != "" && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: azp mismatch", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// discover はプロバイダーの設定情報を取得します（成功した結果はキャッシュする）
+func (p *Provider) discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	cached := p.discovery
+	p.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var discovery Discovery
+	endpoint := strings.TrimRight(p.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, endpoint, &discovery); err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	// 設定情報のissuerは設定したissuerと完全に一致する必要がある（OpenID Connect Discovery 4.3）
+	if discovery.Issuer != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", discovery.Issuer, p.cfg.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 同時に取得した場合は先にキャッシュした結果を使う
+	if p.discovery == nil {
+		p.discovery = &discovery
+	}
+	return p.discovery, nil
+}
+
+// keyFor はIDトークンのkidとアルゴリズムに対応する公開鍵を返します
+// 未知のkidの場合は、プロバイダーの鍵のローテーションに追従するためJWKSを再取得します
+func (p *Provider) keyFor(ctx context.Context, jwksURI string, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	alg := token.Method.Alg()
+
+	key, ok, err := p.findKey(ctx, jwksURI, kid)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if !keyMatches(key, alg) {
+		return nil, fmt.Errorf("signing key %q cannot be used with %s", kid, alg)
+	}
+	return key.key, nil
+}
+
+// findKey はkidの鍵を返します。見つからない場合は前回の取得からjwksRefreshMinWait経過していればJWKSを再取得します
+// 同時に未知のkidのトークンを検証する場合は1つのリクエストだけが取得し、他は取得の完了を待ちます
+func (p *Provider) findKey(ctx context.Context, jwksURI, kid string) (publicKey, bool, error) {
+	p.mu.Lock()
+	if key, ok := p.lookupKey(kid); ok {
+		p.mu.Unlock()
+		return key, true, nil
+	}
+	if fetching := p.keysFetching; fetching != nil {
+		p.mu.Unlock()
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return publicKey{}, false, ctx.Err()
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		key, ok := p.lookupKey(kid)
+		return key, ok, nil
+	}
+	if time.Since(p.keysFetchedAt) < jwksRefreshMinWait {
+		p.mu.Unlock()
+		return publicKey{}, false, nil
+	}
+	fetching := make(chan struct{})
+	p.keysFetching = fetching
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+
+	keys, err := p.fetchKeys(ctx, jwksURI)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keysFetching = nil
+	close(fetching)
+	if err != nil {
+		return publicKey{}, false, err
+	}
+	p.keys = keys
+	key, ok := p.lookupKey(kid)
+	return key, ok, nil
+}
+
+// lookupKey はkidの鍵を返します（p.muを保持して呼び出す）。kidのないトークンは鍵が1つだけの場合に限り受け付けます
+func (p *Provider) lookupKey(kid string) (publicKey, bool) {
+	if kid == "" {
+		if len(p.keys) != 1 {
+			return publicKey{}, false
+		}
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// keyMatches は鍵の種類（とJWKのalg）がトークンのアルゴリズムに合うかを返します
+func keyMatches(key publicKey, alg string) bool {
+	if key.alg != "" && key.alg != alg {
+		return false
+	}
+	switch key.key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS")
+	case *ecdsa.PublicKey:
+		return alg == "ES256"
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	}
+	return false
+}
+
+// fetchKeys はJWKSを取得して署名に使える鍵を返します
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]publicKey, error) {
+	var set struct {
+		Keys []struct {
+			KeyType   string `json:"kty"`
+			KeyID     string `json:"kid"`
+			Use       string `json:"use"`
+			Algorithm string `json:"alg"`
+			N         string `json:"n"`
+			E         string `json:"e"`
+			Curve     string `json:"crv"`
+			X         string `json:"x"`
+			Y         string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks fetch failed: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var key crypto.PublicKey
+		switch jwk.KeyType {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				continue
+			}
+			key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if jwk.Curve != "P-256" {
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+				continue
+			}
+			key = pub
+		case "OKP":
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if jwk.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+				continue
+			}
+			key = ed25519.PublicKey(x)
+		default:
+			continue
+		}
+		keys[jwk.KeyID] = publicKey{alg: jwk.Algorithm, key: key}
+	}
+	return keys, nil
+}
+
+// getJSON はURLからJSONを取得します
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
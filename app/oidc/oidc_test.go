@@ -0,0 +1,361 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend/oidc"
+	"backend/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "test-client"
+	testRedirectURL = "http://localhost/callback"
+	testNonce       = "test-nonce"
+)
+
+// newTestIssuer はテスト用のIssuerとそれを使うProviderを作成します（テスト終了時に停止する）
+func newTestIssuer(t *testing.T) (*oidctest.Issuer, *oidc.Provider) {
+	t.Helper()
+	issuer, err := oidctest.NewIssuer(testClientID, "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(issuer.Close)
+	return issuer, oidc.NewProvider(issuer.Config("test", testRedirectURL), nil)
+}
+
+// authorize はverifierのcode_challengeで認可リクエストを行い、認可コードを返します
+func authorize(t *testing.T, provider *oidc.Provider, issuer *oidctest.Issuer, verifier string) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), "test-state", testNonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := issuer.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "test-state" {
+		t.Fatalf("state = %q, want %q", state, "test-state")
+	}
+	return code
+}
+
+// idTokenClaims は検証に通るIDトークンのクレームです
+func idTokenClaims(issuer *oidctest.Issuer) *oidc.Claims {
+	now := time.Now()
+	return &oidc.Claims{
+		Email:         "user@example.com",
+		EmailVerified: true,
+		Nonce:         testNonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer.URL,
+			Subject:   "user-1",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	}
+}
+
+func TestExchange(t *testing.T) {
+	issuer, provider := newTestIssuer(t)
+	issuer.SetUser(oidctest.User{Subject: "user-42", Email: "alice@example.com", EmailVerified: true, Name: "Alice"})
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := authorize(t, provider, issuer, verifier)
+
+	claims, err := provider.Exchange(context.Background(), code, verifier, testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-42" || claims.Email != "alice@example.com" || !bool(claims.EmailVerified) || claims.Name != "Alice" {
+		t.Errorf("Exchange() = %+v", claims)
+	}
+
+	// 認可コードは1回限り有効
+	if _, err := provider.Exchange(context.Background(), code, verifier, testNonce); !errors.Is(err, oidc.ErrTokenRejected) {
+		t.Errorf("Exchange(reused code) error = %v, want %v", err, oidc.ErrTokenRejected)
+	}
+}
+
+func TestExchangePKCEMismatch(t *testing.T) {
+	issuer, provider := newTestIssuer(t)
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := oidc.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := authorize(t, provider, issuer, verifier)
+
+	if _, err := provider.Exchange(context.Background(), code, other, testNonce); !errors.Is(err, oidc.ErrTokenRejected) {
+		t.Errorf("Exchange(other verifier) error = %v, want %v", err, oidc.ErrTokenRejected)
+	}
+}
+
+func TestExchangeNonceMismatch(t *testing.T) {
+	issuer, provider := newTestIssuer(t)
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := authorize(t, provider, issuer, verifier)
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "other-nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("Exchange(other nonce) error = %v, want %v", err, oidc.ErrInvalidIDToken)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	issuer, _ := newTestIssuer(t)
+
+	// 別のissuerを名乗る設定情報を返すサーバー
+	impostor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidc.Discovery{
+			Issuer:                issuer.URL,
+			AuthorizationEndpoint: issuer.URL + "/authorize",
+			TokenEndpoint:         issuer.URL + "/token",
+			JWKSURI:               issuer.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(impostor.Close)
+
+	tests := []struct {
+		name      string
+		issuerURL string
+	}{
+		{"trailing slash", issuer.URL + "/"},
+		{"different issuer", impostor.URL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := issuer.Config("test", testRedirectURL)
+			cfg.IssuerURL = tt.issuerURL
+			provider := oidc.NewProvider(cfg, nil)
+
+			_, err := provider.AuthCodeURL(context.Background(), "state", testNonce, "verifier")
+			if err == nil || !strings.Contains(err.Error(), "does not match") {
+				t.Errorf("AuthCodeURL() error = %v, want issuer mismatch", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenClaims(t *testing.T) {
+	issuer, provider := newTestIssuer(t)
+
+	tests := []struct {
+		name    string
+		modify  func(claims *oidc.Claims)
+		nonce   string
+		wantErr bool
+	}{
+		{"valid", func(*oidc.Claims) {}, testNonce, false},
+		{"azp matches client", func(claims *oidc.Claims) { claims.AuthorizedParty = testClientID }, testNonce, false},
+		{"nonce mismatch", func(*oidc.Claims) {}, "other-nonce", true},
+		{"missing nonce", func(claims *oidc.Claims) { claims.Nonce = "" }, testNonce, true},
+		{"empty expected nonce", func(claims *oidc.Claims) { claims.Nonce = "" }, "", true},
+		{"other audience", func(claims *oidc.Claims) { claims.Audience = jwt.ClaimStrings{"other-client"} }, testNonce, true},
+		{"other azp", func(claims *oidc.Claims) {
+			claims.Audience = jwt.ClaimStrings{testClientID, "other-client"}
+			claims.AuthorizedParty = "other-client"
+		}, testNonce, true},
+		{"other issuer", func(claims *oidc.Claims) { claims.Issuer = "https://accounts.example.com" }, testNonce, true},
+		{"missing sub", func(claims *oidc.Claims) { claims.Subject = "" }, testNonce, true},
+		{"expired", func(claims *oidc.Claims) {
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Minute))
+		}, testNonce, true},
+		{"missing exp", func(claims *oidc.Claims) { claims.ExpiresAt = nil }, testNonce, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idTokenClaims(issuer)
+			tt.modify(claims)
+			raw, err := issuer.SignIDToken(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = provider.VerifyIDToken(context.Background(), raw, tt.nonce)
+			if tt.wantErr && !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Errorf("VerifyIDToken() error = %v, want %v", err, oidc.ErrInvalidIDToken)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("VerifyIDToken() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsHMAC(t *testing.T) {
+	issuer, provider := newTestIssuer(t)
+
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, idTokenClaims(issuer)).SignedString([]byte(issuer.ClientSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.VerifyIDToken(context.Background(), raw, testNonce); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken(HS256) error = %v, want %v", err, oidc.ErrInvalidIDToken)
+	}
+}
+
+func TestVerifyIDTokenKeyRotation(t *testing.T) {
+	issuer, provider := newTestIssuer(t)
+	verify := func() error {
+		t.Helper()
+		raw, err := issuer.SignIDToken(idTokenClaims(issuer))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = provider.VerifyIDToken(context.Background(), raw, testNonce)
+		return err
+	}
+
+	if err := verify(); err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	if err := issuer.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 直前にJWKSを取得している場合は、未知のkidでも再取得しない
+	if err := verify(); !errors.Is(err, oidc.ErrInvalidIDToken) || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Errorf("VerifyIDToken(new key within refresh wait) error = %v, want unknown signing key", err)
+	}
+
+	// 最小間隔が過ぎていればJWKSを再取得して新しい鍵で検証する
+	provider.ExpireKeys()
+	if err := verify(); err != nil {
+		t.Errorf("VerifyIDToken(new key after refresh wait) error = %v", err)
+	}
+}
+
+// blockingTransport はblockが閉じられるまでJWKSのリクエストを止めるhttp.RoundTripperです
+type blockingTransport struct {
+	blocking atomic.Bool
+	block    chan struct{}
+	started  chan struct{}
+	fetches  atomic.Int32
+}
+
+func (b *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, "/jwks") {
+		b.fetches.Add(1)
+		if b.blocking.Load() {
+			b.started <- struct{}{}
+			<-b.block
+		}
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestVerifyIDTokenDoesNotBlockDuringKeyFetch(t *testing.T) {
+	issuer, err := oidctest.NewIssuer(testClientID, "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(issuer.Close)
+	transport := &blockingTransport{block: make(chan struct{}), started: make(chan struct{}, 1)}
+	provider := oidc.NewProvider(issuer.Config("test", testRedirectURL), &http.Client{Transport: transport})
+
+	sign := func() string {
+		t.Helper()
+		raw, err := issuer.SignIDToken(idTokenClaims(issuer))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	oldToken := sign()
+	if _, err := provider.VerifyIDToken(context.Background(), oldToken, testNonce); err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	if err := issuer.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+	newToken := sign()
+	provider.ExpireKeys()
+	transport.blocking.Store(true)
+
+	// 新しい鍵のトークンを同時に検証する（JWKSの取得は1回だけ行い、取得が終わるまで待つ）
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for n := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[n] = provider.VerifyIDToken(context.Background(), newToken, testNonce)
+		}()
+	}
+	<-transport.started
+
+	// JWKSの取得中でも既知の鍵のトークンはすぐに検証できる
+	done := make(chan error, 1)
+	go func() {
+		_, err := provider.VerifyIDToken(context.Background(), oldToken, testNonce)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("VerifyIDToken(known key) error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("VerifyIDToken(known key) blocked while JWKS was being fetched")
+	}
+
+	close(transport.block)
+	wg.Wait()
+	for n, err := range errs {
+		if err != nil {
+			t.Errorf("VerifyIDToken(new key) #%d error = %v", n, err)
+		}
+	}
+	if fetches := transport.fetches.Load(); fetches != 2 {
+		t.Errorf("JWKS fetches = %d, want 2", fetches)
+	}
+}
+
+func TestBoolUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    oidc.Bool
+		wantErr bool
+	}{
+		{`true`, true, false},
+		{`false`, false, false},
+		{`"true"`, true, false},
+		{`"false"`, false, false},
+		{`null`, false, false},
+		{`"yes"`, false, true},
+		{`1`, false, true},
+	}
+	for _, tt := range tests {
+		var got struct {
+			EmailVerified oidc.Bool `json:"email_verified"`
+		}
+		err := json.Unmarshal([]byte(`{"email_verified":`+tt.input+`}`), &got)
+		if (err != nil) != tt.wantErr || got.EmailVerified != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v, error %v", tt.input, got.EmailVerified, err, tt.want, tt.wantErr)
+		}
+	}
+}
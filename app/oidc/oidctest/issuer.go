@@ -0,0 +1,311 @@
+// Package oidctest はプロセス内で動くOpenID Connectプロバイダーの代わりを提供します
+// 実際のGoogleやAppleに接続せずに、ログインの流れを開発環境や検証で確認するために使用します
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"backend/config"
+	"backend/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 発行するトークンのパラメータ
+const (
+	codeTTL    = time.Minute
+	idTokenTTL = 5 * time.Minute
+	keySize    = 2048
+)
+
+// User は認可リクエストで「ログインする」ユーザーです
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// authRequest は発行した認可コードに紐付く認可リクエストです
+type authRequest struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+	user        User
+	expiresAt   time.Time
+}
+
+// Issuer はhttptest.Serverで動くOpenID Connectプロバイダーです
+// 認可エンドポイントは同意画面を出さずに、SetUserで設定したユーザーの認可コードを発行します
+type Issuer struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+
+	server *httptest.Server
+
+	mu    sync.Mutex
+	key   *rsa.PrivateKey
+	keyID string
+	user  User
+	codes map[string]authRequest
+}
+
+// NewIssuer はIssuerを起動します。使い終わったらCloseを呼び出してください
+func NewIssuer(clientID, clientSecret string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := &Issuer{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		keyID:        newKeyID(),
+		user:         User{Subject: "user-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		codes:        make(map[string]authRequest),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.handleDiscovery)
+	mux.HandleFunc("/jwks", issuer.handleJWKS)
+	mux.HandleFunc("/authorize", issuer.handleAuthorize)
+	mux.HandleFunc("/token", issuer.handleToken)
+	issuer.server = httptest.NewServer(mux)
+	issuer.URL = issuer.server.URL
+	return issuer, nil
+}
+
+// Close はサーバーを停止します
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// SetUser は以降の認可リクエストでログインするユーザーを設定します
+func (i *Issuer) SetUser(user User) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.user = user
+}
+
+// RotateKey は署名鍵を新しいkidの鍵に置き換えます（JWKSも新しい鍵だけを返すようになる）
+func (i *Issuer) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.key = key
+	i.keyID = newKeyID()
+	return nil
+}
+
+// Config はこのIssuerを使うプロバイダーの設定を返します
+func (i *Issuer) Config(name, redirectURL string) config.OIDCProviderConfig {
+	return config.OIDCProviderConfig{
+		Name:         name,
+		IssuerURL:    i.URL,
+		ClientID:     i.ClientID,
+		ClientSecret: i.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+// Authorize はブラウザの代わりに認可URLを開き、リダイレクト先に付いた認可コードとstateを返します
+func (i *Issuer) Authorize(authURL string) (code, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return "", "", fmt.Errorf("authorize returned status %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+	query := location.Query()
+	if e := query.Get("error"); e != "" {
+		return "", "", errors.New(e)
+	}
+	return query.Get("code"), query.Get("state"), nil
+}
+
+// SignIDToken は任意のクレームのIDトークンに署名します（不正なトークンの確認用）
+func (i *Issuer) SignIDToken(claims jwt.Claims) (string, error) {
+	i.mu.Lock()
+	key, keyID := i.key, i.keyID
+	i.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(key)
+}
+
+// handleDiscovery は設定情報を返します
+func (i *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Discovery{
+		Issuer:                i.URL,
+		AuthorizationEndpoint: i.URL + "/authorize",
+		TokenEndpoint:         i.URL + "/token",
+		JWKSURI:               i.URL + "/jwks",
+	})
+}
+
+// handleJWKS は署名鍵の公開鍵を返します
+func (i *Issuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	pub, keyID := i.key.PublicKey, i.keyID
+	i.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// handleAuthorize は認可リクエストを検証し、認可コードを付けてredirect_uriにリダイレクトします
+func (i *Issuer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("client_id") != i.ClientID || redirectURI == "" {
+		http.Error(w, "invalid client_id or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	params := target.Query()
+	params.Set("state", query.Get("state"))
+	switch {
+	case query.Get("response_type") != "code":
+		params.Set("error", "unsupported_response_type")
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		params.Set("error", "invalid_request")
+	default:
+		code := rand.Text()
+		i.mu.Lock()
+		i.codes[code] = authRequest{
+			redirectURI: redirectURI,
+			nonce:       query.Get("nonce"),
+			challenge:   query.Get("code_challenge"),
+			user:        i.user,
+			expiresAt:   time.Now().Add(codeTTL),
+		}
+		i.mu.Unlock()
+		params.Set("code", code)
+	}
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// handleToken は認可コードとcode_verifierを検証し、IDトークンを発行します（認可コードは1回限り有効）
+func (i *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != i.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(i.ClientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	i.mu.Lock()
+	request, found := i.codes[code]
+	delete(i.codes, code)
+	i.mu.Unlock()
+	if !found || time.Now().After(request.expiresAt) || request.redirectURI != r.PostForm.Get("redirect_uri") ||
+		oidc.Challenge(r.PostForm.Get("code_verifier")) != request.challenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	idToken, err := i.SignIDToken(&oidc.Claims{
+		Email:         request.user.Email,
+		EmailVerified: oidc.Bool(request.user.EmailVerified),
+		Name:          request.user.Name,
+		Nonce:         request.nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    i.URL,
+			Subject:   request.user.Subject,
+			Audience:  jwt.ClaimStrings{i.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(idTokenTTL)),
+		},
+	})
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// newKeyID は署名鍵のkidを生成します
+func newKeyID() string {
+	return "oidctest-" + rand.Text()[:8]
+}
+
+// tokenError はトークンエンドポイントのエラーレスポンス（RFC 6749 5.2）を返します
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+// writeJSON はJSONレスポンスを書き込みます
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
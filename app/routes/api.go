@@ -3,6 +3,7 @@ package routes
 import (
 	"backend/auth"
 	"backend/handlers"
+	"backend/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +21,85 @@ func setupAPIRoutes(r *gin.Engine) {
 		{
 			protected.GET("/profile", auth.ProfileHandler)
 			protected.PUT("/profile", auth.UpdateProfileHandler)
+			protected.PUT("/profile/password", auth.ChangePasswordHandler)
+			protected.PUT("/profile/email", auth.ChangeEmailHandler)
+			protected.POST("/profile/mfa/totp", auth.EnrollTOTPHandler)
+			protected.POST("/profile/mfa/totp/confirm", auth.ConfirmTOTPHandler)
+			protected.DELETE("/profile/mfa", auth.DisableMFAHandler)
+			protected.POST("/profile/mfa/recovery-codes", auth.RegenerateRecoveryCodesHandler)
+
+			// セッション（ログイン中の端末）
+			protected.GET("/sessions", auth.ListSessionsHandler)
+			protected.DELETE("/sessions/:id", auth.RevokeSessionHandler)
+
+			// 個人APIキー（APIキー自体では操作できない）
+			protected.GET("/api-keys", auth.ListAPIKeysHandler)
+			protected.POST("/api-keys", auth.RequireVerifiedEmail(auth.VerificationPolicyRestrict), auth.CreateAPIKeyHandler)
+			protected.DELETE("/api-keys/:id", auth.RevokeAPIKeyHandler)
+
+			// メールアドレスの確認が必要なルート（ポリシーがstrictの場合）
+			// 一括インポートやエクスポートはポリシーがrestrictの場合も確認が必要
+			verified := protected.Group("/")
+			verified.Use(auth.RequireVerifiedEmail(auth.VerificationPolicyStrict))
+			{
+				// 単語
+				verified.GET("/words", handlers.ListWordsHandler)
+				verified.GET("/words/export", auth.RequireVerifiedEmail(auth.VerificationPolicyRestrict), handlers.ExportWordsHandler)
+				verified.POST("/words/import", auth.RequireVerifiedEmail(auth.VerificationPolicyRestrict), handlers.ImportWordsHandler)
+				verified.GET("/words/:id", handlers.GetWordHandler)
+				verified.POST("/words", handlers.CreateWordHandler)
+				verified.PUT("/words/:id", handlers.UpdateWordHandler)
+				verified.DELETE("/words/:id", handlers.DeleteWordHandler)
+
+				// カテゴリ
+				verified.GET("/categories", handlers.ListCategoriesHandler)
+				verified.GET("/categories/:id", handlers.GetCategoryHandler)
+				verified.GET("/categories/:id/words", handlers.ListCategoryWordsHandler)
+
+				// 復習（間隔反復）
+				verified.GET("/reviews/due", handlers.GetDueReviewsHandler)
+				verified.POST("/reviews/:word_id", handlers.SubmitReviewHandler)
+
+				// クイズ
+				verified.GET("/quizzes", handlers.ListQuizzesHandler)
+				verified.POST("/quizzes", handlers.CreateQuizHandler)
+				verified.GET("/quizzes/:id", handlers.GetQuizHandler)
+				verified.POST("/quizzes/:id/answers", handlers.AnswerQuizHandler)
+				verified.POST("/quizzes/:id/complete", handlers.CompleteQuizHandler)
+
+				// デッキ
+				verified.GET("/decks", handlers.ListDecksHandler)
+				verified.POST("/decks", handlers.CreateDeckHandler)
+				verified.POST("/decks/import/anki", auth.RequireVerifiedEmail(auth.VerificationPolicyRestrict), handlers.ImportAnkiDeckHandler)
+				verified.GET("/decks/:id", handlers.GetDeckHandler)
+				verified.PUT("/decks/:id", handlers.UpdateDeckHandler)
+				verified.DELETE("/decks/:id", handlers.DeleteDeckHandler)
+				verified.POST("/decks/:id/words", handlers.AddDeckWordsHandler)
+				verified.PUT("/decks/:id/words/order", handlers.ReorderDeckWordsHandler)
+				verified.DELETE("/decks/:id/words/:word_id", handlers.RemoveDeckWordHandler)
+				verified.GET("/decks/:id/reviews/due", handlers.GetDeckDueReviewsHandler)
+				verified.POST("/decks/:id/quizzes", handlers.CreateDeckQuizHandler)
+				verified.GET("/decks/:id/export/anki", auth.RequireVerifiedEmail(auth.VerificationPolicyRestrict), handlers.ExportDeckAnkiHandler)
+			}
+
+			// 管理用ルート（システム単語とカテゴリは編集者以上、ユーザーは管理者のみ）
+			admin := protected.Group("/admin")
+			admin.Use(auth.RequireRole(models.RoleEditor))
+			{
+				admin.POST("/words", handlers.CreateSystemWordHandler)
+				admin.POST("/words/import", handlers.ImportSystemWordsHandler)
+				admin.PUT("/words/:id", handlers.UpdateSystemWordHandler)
+				admin.DELETE("/words/:id", handlers.DeleteSystemWordHandler)
+
+				admin.POST("/categories", handlers.CreateCategoryHandler)
+				admin.PUT("/categories/:id", handlers.UpdateCategoryHandler)
+				admin.DELETE("/categories/:id", handlers.DeleteCategoryHandler)
+
+				admin.GET("/users", auth.RequireRole(models.RoleAdmin), handlers.ListUsersHandler)
+				admin.GET("/users/:id", auth.RequireRole(models.RoleAdmin), handlers.GetUserHandler)
+				admin.PUT("/users/:id/role", auth.RequireRole(models.RoleAdmin), handlers.UpdateUserRoleHandler)
+				admin.DELETE("/users/:id", auth.RequireRole(models.RoleAdmin), handlers.DeleteUserHandler)
+			}
 		}
 	}
 }
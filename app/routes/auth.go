@@ -12,5 +12,20 @@ func setupAuthRoutes(r *gin.Engine) {
 	{
 		authGroup.POST("/register", auth.RegisterHandler)
 		authGroup.POST("/login", auth.LoginHandler)
+		authGroup.POST("/login/mfa", auth.MFALoginHandler)
+		authGroup.POST("/refresh", auth.RefreshHandler)
+		authGroup.POST("/logout", auth.Middleware(), auth.LogoutHandler)
+		authGroup.POST("/password/forgot", auth.ForgotPasswordHandler)
+		authGroup.POST("/password/reset", auth.ResetPasswordHandler)
+		authGroup.POST("/verify-email", auth.VerifyEmailHandler)
+		authGroup.POST("/verify-email/resend", auth.Middleware(), auth.ResendVerificationHandler)
+
+		// ログインリンク（パスワードなしのログイン）
+		authGroup.POST("/magic-link", auth.RequestMagicLinkHandler)
+		authGroup.POST("/magic-link/consume", auth.ConsumeMagicLinkHandler)
+
+		// 外部プロバイダー（OpenID Connect）でのログイン
+		authGroup.POST("/oidc/:provider/start", auth.OIDCStartHandler)
+		authGroup.POST("/oidc/:provider/callback", auth.OIDCCallbackHandler)
 	}
 }
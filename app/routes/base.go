@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"backend/auth"
 	"backend/handlers"
 
 	"github.com/gin-gonic/gin"
@@ -13,4 +14,7 @@ func setupBaseRoutes(r *gin.Engine) {
 
 	// ヘルスチェックエンドポイント
 	r.GET("/health", handlers.HealthHandler)
+
+	// アクセストークン検証用の公開鍵
+	r.GET("/.well-known/jwks.json", auth.JWKSHandler)
 }
@@ -8,9 +8,14 @@ import (
 )
 
 // SetupRouter はGinルーターを設定し、全てのルートを登録します
-func SetupRouter(cfg *config.Config) *gin.Engine {
+func SetupRouter(cfg *config.Config) (*gin.Engine, error) {
 	r := gin.Default()
 
+	// 信頼するプロキシ（未設定の場合はX-Forwarded-Forを無視し、c.ClientIP()は接続元のIPアドレスになる）
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, err
+	}
+
 	// CORS設定
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = cfg.Server.AllowOrigins
@@ -27,5 +32,5 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	// API v1 ルートを設定
 	setupAPIRoutes(r)
 
-	return r
+	return r, nil
 }
@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testConfig はルーターの作成に必要な最小限の設定を返します
+func testConfig(trustedProxies []string) *config.Config {
+	return &config.Config{Server: config.ServerConfig{
+		AllowOrigins:   []string{"http://localhost:3000"},
+		TrustedProxies: trustedProxies,
+	}}
+}
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		want           string
+	}{
+		{"no trusted proxies ignores the header", nil, "203.0.113.7:5000", "203.0.113.7"},
+		{"trusted proxy forwards the client address", []string{"10.0.0.0/8"}, "10.0.0.2:5000", "198.51.100.1"},
+		{"untrusted connection ignores the header", []string{"10.0.0.0/8"}, "203.0.113.7:5000", "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := SetupRouter(testConfig(tt.trustedProxies))
+			if err != nil {
+				t.Fatalf("SetupRouter() error = %v", err)
+			}
+			r.GET("/test/client-ip", func(c *gin.Context) {
+				c.String(http.StatusOK, c.ClientIP())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test/client-ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetupRouterInvalidTrustedProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	if _, err := SetupRouter(testConfig([]string{"not-an-ip"})); err == nil {
+		t.Error("SetupRouter() error = nil, want error")
+	}
+}
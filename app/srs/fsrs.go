@@ -0,0 +1,168 @@
+package srs
+
+import (
+	"math"
+	"time"
+)
+
+// FSRSのパラメータ（FSRS-4.5の既定の重み）
+var DefaultFSRSWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+const (
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0
+
+	// DefaultRequestRetention は目標とする想起確率です
+	DefaultRequestRetention = 0.9
+	// MaxFSRSIntervalDays は間隔日数の上限です
+	MaxFSRSIntervalDays = 36500
+
+	minFSRSDifficulty = 1.0
+	maxFSRSDifficulty = 10.0
+	minFSRSStability  = 0.1
+)
+
+// FSRSの評価（Again/Hard/Good/Easy）
+const (
+	fsrsAgain = 1
+	fsrsHard  = 2
+	fsrsGood  = 3
+	fsrsEasy  = 4
+)
+
+// FSRS はFree Spaced Repetition Schedulerの実装です
+// 記憶の安定性(Stability)・難易度(Difficulty)・想起確率(Retrievability)で間隔を決定します
+type FSRS struct {
+	Weights          [17]float64
+	RequestRetention float64
+}
+
+// NewFSRS は既定のパラメータでFSRSを作成します
+func NewFSRS() FSRS {
+	return FSRS{Weights: DefaultFSRSWeights, RequestRetention: DefaultRequestRetention}
+}
+
+// Name はアルゴリズム名を返します
+func (FSRS) Name() string {
+	return AlgorithmFSRS
+}
+
+// NewCard は未学習カードの初期状態を返します
+func (FSRS) NewCard() Card {
+	return Card{Algorithm: AlgorithmFSRS}
+}
+
+// Migrate は現在の間隔日数を安定性、易しさ係数を難易度として換算します
+// 目標想起確率0.9では間隔日数と安定性が等しくなるため、間隔をそのまま引き継げます
+func (f FSRS) Migrate(card Card) Card {
+	next := card
+	next.Algorithm = AlgorithmFSRS
+	if card.LastReviewedAt == nil {
+		next.Stability = 0
+		next.Difficulty = 0
+		return next
+	}
+
+	next.Stability = math.Max(float64(card.IntervalDays), minFSRSStability)
+	easeFactor := card.EaseFactor
+	if easeFactor == 0 {
+		easeFactor = DefaultEaseFactor
+	}
+	next.Difficulty = clamp(5-(easeFactor-DefaultEaseFactor)/0.2, minFSRSDifficulty, maxFSRSDifficulty)
+	return next
+}
+
+// Schedule はFSRSで次の状態と次回復習日時を計算します
+func (f FSRS) Schedule(card Card, grade int, now time.Time) (Card, time.Time) {
+	next := card
+	next.Algorithm = AlgorithmFSRS
+	rating := FSRSRating(grade)
+
+	if next.Stability <= 0 || next.LastReviewedAt == nil {
+		next.Stability = f.initialStability(rating)
+		next.Difficulty = f.initialDifficulty(rating)
+	} else {
+		elapsed := math.Max(now.Sub(*next.LastReviewedAt).Hours()/24, 0)
+		r := Retrievability(elapsed, next.Stability)
+		next.Difficulty = f.nextDifficulty(next.Difficulty, rating)
+		if rating == fsrsAgain {
+			next.Stability = f.forgetStability(card.Difficulty, card.Stability, r)
+		} else {
+			next.Stability = f.recallStability(card.Difficulty, card.Stability, r, rating)
+		}
+	}
+	recordOutcome(&next, grade, now)
+
+	next.IntervalDays = f.nextInterval(next.Stability)
+	return next, dueAt(now, next.IntervalDays)
+}
+
+// FSRSRating は0〜5の評価をFSRSの4段階評価に変換します
+func FSRSRating(grade int) int {
+	switch {
+	case grade < PassingGrade:
+		return fsrsAgain
+	case grade == 3:
+		return fsrsHard
+	case grade == 4:
+		return fsrsGood
+	default:
+		return fsrsEasy
+	}
+}
+
+// Retrievability は経過日数と安定性から想起確率を計算します
+func Retrievability(elapsedDays, stability float64) float64 {
+	if stability <= 0 {
+		return 0
+	}
+	return math.Pow(1+fsrsFactor*elapsedDays/stability, fsrsDecay)
+}
+
+func (f FSRS) initialStability(rating int) float64 {
+	return math.Max(f.Weights[rating-1], minFSRSStability)
+}
+
+func (f FSRS) initialDifficulty(rating int) float64 {
+	w := f.Weights
+	return clamp(w[4]-float64(rating-3)*w[5], minFSRSDifficulty, maxFSRSDifficulty)
+}
+
+func (f FSRS) nextDifficulty(d float64, rating int) float64 {
+	w := f.Weights
+	next := d - w[6]*float64(rating-3)
+	// 平均への回帰
+	next = w[7]*f.initialDifficulty(fsrsGood) + (1-w[7])*next
+	return clamp(next, minFSRSDifficulty, maxFSRSDifficulty)
+}
+
+func (f FSRS) recallStability(d, s, r float64, rating int) float64 {
+	w := f.Weights
+	hardPenalty := 1.0
+	if rating == fsrsHard {
+		hardPenalty = w[15]
+	}
+	easyBonus := 1.0
+	if rating == fsrsEasy {
+		easyBonus = w[16]
+	}
+	return s * (1 + math.Exp(w[8])*(11-d)*math.Pow(s, -w[9])*(math.Exp(w[10]*(1-r))-1)*hardPenalty*easyBonus)
+}
+
+func (f FSRS) forgetStability(d, s, r float64) float64 {
+	w := f.Weights
+	next := w[11] * math.Pow(d, -w[12]) * (math.Pow(s+1, w[13]) - 1) * math.Exp(w[14]*(1-r))
+	return math.Max(math.Min(next, s), minFSRSStability)
+}
+
+func (f FSRS) nextInterval(stability float64) int {
+	retention := f.RequestRetention
+	if retention <= 0 || retention >= 1 {
+		retention = DefaultRequestRetention
+	}
+	interval := stability / fsrsFactor * (math.Pow(retention, 1/fsrsDecay) - 1)
+	return int(clamp(math.Round(interval), 1, MaxFSRSIntervalDays))
+}
@@ -0,0 +1,130 @@
+package srs
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFSRSRating(t *testing.T) {
+	want := map[int]int{0: fsrsAgain, 1: fsrsAgain, 2: fsrsAgain, 3: fsrsHard, 4: fsrsGood, 5: fsrsEasy}
+	for grade, rating := range want {
+		if got := FSRSRating(grade); got != rating {
+			t.Errorf("FSRSRating(%d) = %d, want %d", grade, got, rating)
+		}
+	}
+}
+
+func TestRetrievability(t *testing.T) {
+	tests := []struct {
+		elapsed, stability, want float64
+	}{
+		{0, 10, 1},
+		{10, 10, 0.9}, // 安定性は想起確率が90%になる日数
+		{5, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := Retrievability(tt.elapsed, tt.stability); !approxEqual(got, tt.want) {
+			t.Errorf("Retrievability(%v, %v) = %v, want %v", tt.elapsed, tt.stability, got, tt.want)
+		}
+	}
+}
+
+func TestFSRSScheduleNewCard(t *testing.T) {
+	w := DefaultFSRSWeights
+	tests := []struct {
+		name           string
+		grade          int
+		wantStability  float64
+		wantDifficulty float64
+		wantInterval   int
+	}{
+		{"again", 1, w[0], w[4] + 2*w[5], 1},
+		{"hard", 3, w[1], w[4] + w[5], 1},
+		{"good", 4, w[2], w[4], 4},
+		{"easy", 5, w[3], w[4] - w[5], 14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, due := NewFSRS().Schedule(NewFSRS().NewCard(), tt.grade, testNow)
+			if !approxEqual(got.Stability, tt.wantStability) || !approxEqual(got.Difficulty, tt.wantDifficulty) {
+				t.Errorf("stability, difficulty = %v, %v, want %v, %v", got.Stability, got.Difficulty, tt.wantStability, tt.wantDifficulty)
+			}
+			if got.IntervalDays != tt.wantInterval || !due.Equal(testNow.AddDate(0, 0, tt.wantInterval)) {
+				t.Errorf("interval = %d (due %v), want %d", got.IntervalDays, due, tt.wantInterval)
+			}
+		})
+	}
+}
+
+func TestFSRSScheduleReview(t *testing.T) {
+	card := Card{Algorithm: AlgorithmFSRS, Stability: 10, Difficulty: 5, IntervalDays: 10, Repetitions: 3, LastReviewedAt: daysAgo(10)}
+	f := NewFSRS()
+
+	hard, _ := f.Schedule(card, 3, testNow)
+	good, _ := f.Schedule(card, 4, testNow)
+	easy, _ := f.Schedule(card, 5, testNow)
+	if !(card.Stability < hard.Stability && hard.Stability < good.Stability && good.Stability < easy.Stability) {
+		t.Errorf("stability after hard/good/easy = %v/%v/%v, want increasing above %v", hard.Stability, good.Stability, easy.Stability, card.Stability)
+	}
+	if !(hard.Difficulty > good.Difficulty && good.Difficulty > easy.Difficulty) {
+		t.Errorf("difficulty after hard/good/easy = %v/%v/%v, want decreasing", hard.Difficulty, good.Difficulty, easy.Difficulty)
+	}
+	// 目標想起確率0.9では間隔日数は安定性を丸めた値になる
+	if want := int(math.Round(good.Stability)); good.IntervalDays != want {
+		t.Errorf("interval = %d, want %d", good.IntervalDays, want)
+	}
+	if good.Repetitions != 4 || good.Lapses != 0 {
+		t.Errorf("repetitions, lapses = %d, %d, want 4, 0", good.Repetitions, good.Lapses)
+	}
+
+	again, _ := f.Schedule(card, 1, testNow)
+	if again.Stability >= card.Stability || again.Stability < minFSRSStability {
+		t.Errorf("stability after again = %v, want in [%v, %v)", again.Stability, minFSRSStability, card.Stability)
+	}
+	if again.Difficulty <= card.Difficulty {
+		t.Errorf("difficulty after again = %v, want above %v", again.Difficulty, card.Difficulty)
+	}
+	if again.Repetitions != 0 || again.Lapses != 1 || again.IntervalDays < 1 {
+		t.Errorf("repetitions, lapses, interval = %d, %d, %d, want 0, 1, >=1", again.Repetitions, again.Lapses, again.IntervalDays)
+	}
+}
+
+func TestFSRSIntervalLimits(t *testing.T) {
+	f := NewFSRS()
+	if got := f.nextInterval(minFSRSStability); got != 1 {
+		t.Errorf("nextInterval(min) = %d, want 1", got)
+	}
+	if got := f.nextInterval(1e9); got != MaxFSRSIntervalDays {
+		t.Errorf("nextInterval(huge) = %d, want %d", got, MaxFSRSIntervalDays)
+	}
+	// 不正な目標想起確率は既定値として扱う
+	if got := (FSRS{Weights: DefaultFSRSWeights, RequestRetention: 1.5}).nextInterval(10); got != 10 {
+		t.Errorf("nextInterval with invalid retention = %d, want 10", got)
+	}
+}
+
+func TestFSRSMigrate(t *testing.T) {
+	tests := []struct {
+		name           string
+		card           Card
+		wantStability  float64
+		wantDifficulty float64
+	}{
+		{"unreviewed card", Card{Algorithm: AlgorithmSM2, EaseFactor: 2.5}, 0, 0},
+		{"default ease factor", Card{Algorithm: AlgorithmSM2, EaseFactor: 2.5, IntervalDays: 20, LastReviewedAt: daysAgo(20)}, 20, 5},
+		{"low ease factor is hard", Card{Algorithm: AlgorithmSM2, EaseFactor: 1.9, IntervalDays: 6, LastReviewedAt: daysAgo(6)}, 6, 8},
+		{"minimum ease factor is capped", Card{Algorithm: AlgorithmSM2, EaseFactor: MinEaseFactor, IntervalDays: 1, LastReviewedAt: daysAgo(1)}, 1, maxFSRSDifficulty},
+		{"leitner card without ease factor", Card{Algorithm: AlgorithmLeitner, Box: 4, IntervalDays: 8, LastReviewedAt: daysAgo(8)}, 8, 5},
+		{"zero interval keeps minimum stability", Card{Algorithm: AlgorithmSM2, EaseFactor: 2.5, LastReviewedAt: daysAgo(0)}, minFSRSStability, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewFSRS().Migrate(tt.card)
+			if got.Algorithm != AlgorithmFSRS || !approxEqual(got.Stability, tt.wantStability) || !approxEqual(got.Difficulty, tt.wantDifficulty) {
+				t.Errorf("Migrate = %s S=%v D=%v, want S=%v D=%v", got.Algorithm, got.Stability, got.Difficulty, tt.wantStability, tt.wantDifficulty)
+			}
+		})
+	}
+}
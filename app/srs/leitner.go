@@ -0,0 +1,71 @@
+package srs
+
+import (
+	"math"
+	"time"
+)
+
+// Leitnerのパラメータ
+const (
+	MinLeitnerBox = 1
+	MaxLeitnerBox = 7
+)
+
+// Leitner はライトナー箱方式の実装です
+// 正解すると次の箱へ進み、不正解で最初の箱に戻ります。箱nの間隔は2^(n-1)日です
+type Leitner struct{}
+
+// Name はアルゴリズム名を返します
+func (Leitner) Name() string {
+	return AlgorithmLeitner
+}
+
+// NewCard は未学習カードの初期状態を返します
+func (Leitner) NewCard() Card {
+	return Card{Algorithm: AlgorithmLeitner, Box: MinLeitnerBox}
+}
+
+// Migrate は現在の間隔日数に最も近い箱を割り当てます
+func (Leitner) Migrate(card Card) Card {
+	next := card
+	next.Algorithm = AlgorithmLeitner
+	if card.Repetitions == 0 || card.IntervalDays <= 1 {
+		next.Box = MinLeitnerBox
+		return next
+	}
+	box := 1 + int(math.Round(math.Log2(float64(card.IntervalDays))))
+	next.Box = int(clamp(float64(box), MinLeitnerBox, MaxLeitnerBox))
+	return next
+}
+
+// Schedule は箱を移動して次回復習日時を計算します
+func (Leitner) Schedule(card Card, grade int, now time.Time) (Card, time.Time) {
+	next := card
+	next.Algorithm = AlgorithmLeitner
+
+	if grade >= PassingGrade {
+		if next.LastReviewedAt != nil {
+			next.Box++
+		}
+		if next.Box < MinLeitnerBox {
+			next.Box = MinLeitnerBox
+		}
+		if next.Box > MaxLeitnerBox {
+			next.Box = MaxLeitnerBox
+		}
+	} else {
+		next.Box = MinLeitnerBox
+	}
+	recordOutcome(&next, grade, now)
+
+	next.IntervalDays = LeitnerInterval(next.Box)
+	return next, dueAt(now, next.IntervalDays)
+}
+
+// LeitnerInterval は箱に対応する間隔日数を返します
+func LeitnerInterval(box int) int {
+	if box < MinLeitnerBox {
+		box = MinLeitnerBox
+	}
+	return 1 << (box - 1)
+}
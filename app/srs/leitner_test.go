@@ -0,0 +1,63 @@
+package srs
+
+import "testing"
+
+func TestLeitnerSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		card         Card
+		grade        int
+		wantBox      int
+		wantInterval int
+	}{
+		{"first review stays in box 1", Leitner{}.NewCard(), 4, 1, 1},
+		{"pass moves to next box", Card{Box: 3, Repetitions: 2, LastReviewedAt: daysAgo(4)}, 4, 4, 8},
+		{"pass in last box stays", Card{Box: MaxLeitnerBox, Repetitions: 6, LastReviewedAt: daysAgo(64)}, 5, MaxLeitnerBox, 64},
+		{"fail returns to box 1", Card{Box: 5, Repetitions: 4, LastReviewedAt: daysAgo(16)}, 2, 1, 1},
+		{"missing box is treated as box 1", Card{}, 3, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, due := Leitner{}.Schedule(tt.card, tt.grade, testNow)
+			if got.Algorithm != AlgorithmLeitner || got.Box != tt.wantBox || got.IntervalDays != tt.wantInterval {
+				t.Errorf("Schedule = %s box %d interval %d, want box %d interval %d",
+					got.Algorithm, got.Box, got.IntervalDays, tt.wantBox, tt.wantInterval)
+			}
+			if want := testNow.AddDate(0, 0, tt.wantInterval); !due.Equal(want) {
+				t.Errorf("due = %v, want %v", due, want)
+			}
+		})
+	}
+}
+
+func TestLeitnerInterval(t *testing.T) {
+	for box, want := range map[int]int{0: 1, 1: 1, 2: 2, 3: 4, 4: 8, 7: 64} {
+		if got := LeitnerInterval(box); got != want {
+			t.Errorf("LeitnerInterval(%d) = %d, want %d", box, got, want)
+		}
+	}
+}
+
+func TestLeitnerMigrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		card    Card
+		wantBox int
+	}{
+		{"unlearned card", Card{Algorithm: AlgorithmSM2, EaseFactor: 2.5}, 1},
+		{"one day interval", Card{Algorithm: AlgorithmSM2, IntervalDays: 1, Repetitions: 1}, 1},
+		{"three days rounds to box 3", Card{Algorithm: AlgorithmSM2, IntervalDays: 3, Repetitions: 2}, 3},
+		{"eight days", Card{Algorithm: AlgorithmSM2, IntervalDays: 8, Repetitions: 3}, 4},
+		{"long interval is capped", Card{Algorithm: AlgorithmFSRS, IntervalDays: 1000, Repetitions: 9}, MaxLeitnerBox},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Leitner{}.Migrate(tt.card)
+			if got.Algorithm != AlgorithmLeitner || got.Box != tt.wantBox {
+				t.Errorf("Migrate = %s box %d, want box %d", got.Algorithm, got.Box, tt.wantBox)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package srs
+
+import (
+	"fmt"
+	"time"
+)
+
+// 評価値の範囲（全アルゴリズム共通で0〜5を受け付ける）
+const (
+	MinGrade     = 0
+	MaxGrade     = 5
+	PassingGrade = 3
+)
+
+// アルゴリズム名（users.scheduling_algorithmに保存される値）
+const (
+	AlgorithmSM2     = "SM2"
+	AlgorithmLeitner = "LEITNER"
+	AlgorithmFSRS    = "FSRS"
+)
+
+// DefaultAlgorithm は新規ユーザーのアルゴリズムです
+const DefaultAlgorithm = AlgorithmSM2
+
+// Card はカードの学習状態です
+// IntervalDays・Repetitions・Lapses・LastReviewedAtは全アルゴリズム共通で更新され、
+// それ以外のフィールドは各アルゴリズム固有の状態です
+type Card struct {
+	Algorithm      string
+	IntervalDays   int
+	Repetitions    int
+	Lapses         int
+	LastReviewedAt *time.Time
+
+	// SM-2
+	EaseFactor float64
+	// Leitner
+	Box int
+	// FSRS
+	Stability  float64
+	Difficulty float64
+}
+
+// Scheduler は間隔反復アルゴリズムのインターフェースです
+type Scheduler interface {
+	// Name はアルゴリズム名を返します
+	Name() string
+	// NewCard は未学習カードの初期状態を返します
+	NewCard() Card
+	// Migrate は他のアルゴリズムで学習したカードの状態を、このアルゴリズムの状態に変換します
+	Migrate(card Card) Card
+	// Schedule は評価を反映した次の状態と次回復習日時を返します
+	Schedule(card Card, grade int, now time.Time) (Card, time.Time)
+}
+
+// Algorithms は選択可能なアルゴリズム名の一覧です
+var Algorithms = []string{AlgorithmSM2, AlgorithmLeitner, AlgorithmFSRS}
+
+// Get はアルゴリズム名に対応するSchedulerを返します
+func Get(name string) (Scheduler, error) {
+	switch name {
+	case AlgorithmSM2, "":
+		return SM2{}, nil
+	case AlgorithmLeitner:
+		return Leitner{}, nil
+	case AlgorithmFSRS:
+		return NewFSRS(), nil
+	default:
+		return nil, fmt.Errorf("unknown scheduling algorithm: %s", name)
+	}
+}
+
+// ValidateGrade は評価値が0〜5の範囲にあるかを検証します
+func ValidateGrade(grade int) error {
+	if grade < MinGrade || grade > MaxGrade {
+		return fmt.Errorf("grade must be between %d and %d", MinGrade, MaxGrade)
+	}
+	return nil
+}
+
+// Prepare はカードを指定したアルゴリズムで扱える状態にします
+// 別のアルゴリズムで学習したカードは状態を変換し、未設定の場合は初期状態にします
+func Prepare(s Scheduler, card Card) Card {
+	if card.Algorithm == s.Name() {
+		return card
+	}
+	return s.Migrate(card)
+}
+
+// recordOutcome は全アルゴリズム共通の連続正解数・忘却回数・最終復習日時を更新します
+func recordOutcome(card *Card, grade int, now time.Time) {
+	if grade >= PassingGrade {
+		card.Repetitions++
+	} else {
+		if card.Repetitions > 0 {
+			card.Lapses++
+		}
+		card.Repetitions = 0
+	}
+	reviewedAt := now
+	card.LastReviewedAt = &reviewedAt
+}
+
+// dueAt は間隔日数から次回復習日時を計算します
+func dueAt(now time.Time, intervalDays int) time.Time {
+	return now.AddDate(0, 0, intervalDays)
+}
+
+// clamp は値を[min, max]の範囲に収めます
+func clamp(value, min, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
@@ -0,0 +1,68 @@
+package srs
+
+import (
+	"math"
+	"time"
+)
+
+// SM-2のパラメータ
+const (
+	DefaultEaseFactor = 2.5
+	MinEaseFactor     = 1.3
+	MaxEaseFactor     = 3.0
+)
+
+// SM2 はSuperMemo-2アルゴリズムの実装です
+type SM2 struct{}
+
+// Name はアルゴリズム名を返します
+func (SM2) Name() string {
+	return AlgorithmSM2
+}
+
+// NewCard は未学習カードの初期状態を返します
+func (SM2) NewCard() Card {
+	return Card{Algorithm: AlgorithmSM2, EaseFactor: DefaultEaseFactor}
+}
+
+// Migrate は他のアルゴリズムの状態から易しさ係数を推定します
+// FSRSの難易度がある場合は難易度5を既定値2.5として線形に換算します
+func (s SM2) Migrate(card Card) Card {
+	next := card
+	next.Algorithm = AlgorithmSM2
+	if card.Algorithm == AlgorithmFSRS && card.Difficulty > 0 {
+		next.EaseFactor = clamp(DefaultEaseFactor-(card.Difficulty-5)*0.2, MinEaseFactor, MaxEaseFactor)
+	} else if next.EaseFactor == 0 {
+		next.EaseFactor = DefaultEaseFactor
+	}
+	return next
+}
+
+// Schedule はSM-2で次の状態と次回復習日時を計算します
+func (SM2) Schedule(card Card, grade int, now time.Time) (Card, time.Time) {
+	next := card
+	next.Algorithm = AlgorithmSM2
+	if next.EaseFactor == 0 {
+		next.EaseFactor = DefaultEaseFactor
+	}
+
+	if grade >= PassingGrade {
+		switch next.Repetitions {
+		case 0:
+			next.IntervalDays = 1
+		case 1:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(next.IntervalDays) * next.EaseFactor))
+		}
+	} else {
+		// 失敗した場合は最初からやり直す
+		next.IntervalDays = 1
+	}
+	recordOutcome(&next, grade, now)
+
+	q := float64(MaxGrade - grade)
+	next.EaseFactor = clamp(next.EaseFactor+0.1-q*(0.08+q*0.02), MinEaseFactor, MaxEaseFactor)
+
+	return next, dueAt(now, next.IntervalDays)
+}
@@ -0,0 +1,73 @@
+package srs
+
+import "testing"
+
+func TestSM2Schedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		card         Card
+		grade        int
+		wantInterval int
+		wantReps     int
+		wantLapses   int
+		wantEase     float64
+	}{
+		{"new card, perfect", SM2{}.NewCard(), 5, 1, 1, 0, 2.6},
+		{"new card, good", SM2{}.NewCard(), 4, 1, 1, 0, 2.5},
+		{"new card, hard", SM2{}.NewCard(), 3, 1, 1, 0, 2.36},
+		{"new card without ease factor", Card{}, 4, 1, 1, 0, 2.5},
+		{"second repetition", Card{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1, LastReviewedAt: daysAgo(1)}, 4, 6, 2, 0, 2.5},
+		{"third repetition multiplies by ease factor", Card{EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2, LastReviewedAt: daysAgo(6)}, 4, 15, 3, 0, 2.5},
+		{"lapse resets repetitions", Card{EaseFactor: 2.5, IntervalDays: 15, Repetitions: 3, LastReviewedAt: daysAgo(15)}, 2, 1, 0, 1, 2.18},
+		{"failing a new card is not a lapse", SM2{}.NewCard(), 1, 1, 0, 0, 1.96},
+		{"ease factor floor", Card{EaseFactor: MinEaseFactor, IntervalDays: 6, Repetitions: 2, LastReviewedAt: daysAgo(6)}, 0, 1, 0, 1, MinEaseFactor},
+		{"ease factor ceiling", Card{EaseFactor: MaxEaseFactor, IntervalDays: 6, Repetitions: 2, LastReviewedAt: daysAgo(6)}, 5, 18, 3, 0, MaxEaseFactor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, due := SM2{}.Schedule(tt.card, tt.grade, testNow)
+			if got.Algorithm != AlgorithmSM2 {
+				t.Errorf("Algorithm = %s, want %s", got.Algorithm, AlgorithmSM2)
+			}
+			if got.IntervalDays != tt.wantInterval || got.Repetitions != tt.wantReps || got.Lapses != tt.wantLapses {
+				t.Errorf("interval, repetitions, lapses = %d, %d, %d, want %d, %d, %d",
+					got.IntervalDays, got.Repetitions, got.Lapses, tt.wantInterval, tt.wantReps, tt.wantLapses)
+			}
+			if !approxEqual(got.EaseFactor, tt.wantEase) {
+				t.Errorf("EaseFactor = %v, want %v", got.EaseFactor, tt.wantEase)
+			}
+			if want := testNow.AddDate(0, 0, tt.wantInterval); !due.Equal(want) {
+				t.Errorf("due = %v, want %v", due, want)
+			}
+			if got.LastReviewedAt == nil || !got.LastReviewedAt.Equal(testNow) {
+				t.Errorf("LastReviewedAt = %v, want %v", got.LastReviewedAt, testNow)
+			}
+		})
+	}
+}
+
+func TestSM2Migrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		card     Card
+		wantEase float64
+	}{
+		{"from FSRS difficulty 5", Card{Algorithm: AlgorithmFSRS, Difficulty: 5, Stability: 10}, 2.5},
+		{"from FSRS difficulty 7", Card{Algorithm: AlgorithmFSRS, Difficulty: 7, Stability: 10}, 2.1},
+		{"from FSRS difficulty 1 is capped", Card{Algorithm: AlgorithmFSRS, Difficulty: 1, Stability: 10}, MaxEaseFactor},
+		{"from FSRS difficulty 10 is floored", Card{Algorithm: AlgorithmFSRS, Difficulty: 10, Stability: 10}, 1.5},
+		{"from unreviewed FSRS card", Card{Algorithm: AlgorithmFSRS}, DefaultEaseFactor},
+		{"keeps existing ease factor", Card{Algorithm: AlgorithmLeitner, Box: 3, EaseFactor: 2.0}, 2.0},
+		{"defaults missing ease factor", Card{Algorithm: AlgorithmLeitner, Box: 3}, DefaultEaseFactor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SM2{}.Migrate(tt.card)
+			if got.Algorithm != AlgorithmSM2 || !approxEqual(got.EaseFactor, tt.wantEase) {
+				t.Errorf("Migrate = %s %v, want %s %v", got.Algorithm, got.EaseFactor, AlgorithmSM2, tt.wantEase)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package srs
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// testNow はテストで使う固定の現在時刻です
+var testNow = time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+
+// daysAgo はtestNowのn日前の時刻を返します
+func daysAgo(n int) *time.Time {
+	t := testNow.AddDate(0, 0, -n)
+	return &t
+}
+
+// approxEqual は浮動小数点数がほぼ等しいかを返します
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", AlgorithmSM2, false},
+		{AlgorithmSM2, AlgorithmSM2, false},
+		{AlgorithmLeitner, AlgorithmLeitner, false},
+		{AlgorithmFSRS, AlgorithmFSRS, false},
+		{"ANKI", "", true},
+	}
+	for _, tt := range tests {
+		s, err := Get(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Get(%q) error = nil, want error", tt.name)
+			}
+			continue
+		}
+		if err != nil || s.Name() != tt.want {
+			t.Errorf("Get(%q) = %v, %v, want %s", tt.name, s, err, tt.want)
+		}
+	}
+}
+
+func TestValidateGrade(t *testing.T) {
+	for grade := -1; grade <= 6; grade++ {
+		err := ValidateGrade(grade)
+		if valid := grade >= MinGrade && grade <= MaxGrade; valid != (err == nil) {
+			t.Errorf("ValidateGrade(%d) = %v", grade, err)
+		}
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	card := Card{Algorithm: AlgorithmLeitner, Box: 3, IntervalDays: 4, Repetitions: 2, LastReviewedAt: daysAgo(1)}
+	if got := Prepare(Leitner{}, card); got != card {
+		t.Errorf("Prepare with the same algorithm changed the card: %+v", got)
+	}
+	if got := Prepare(SM2{}, card); got.Algorithm != AlgorithmSM2 || got.EaseFactor != DefaultEaseFactor {
+		t.Errorf("Prepare(SM2) = %+v, want SM2 card with default ease factor", got)
+	}
+}
@@ -0,0 +1,122 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret はRFC 6238 Appendix BのSHA1の秘密鍵（"12345678901234567890"）をBase32にしたものです
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// Appendix Bは8桁のため、下6桁と比較する
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tt.want[len(tt.want)-Digits:]; got != want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestCodeSecretFormats(t *testing.T) {
+	want, _ := Code(rfcSecret, time.Unix(59, 0))
+	for _, secret := range []string{
+		strings.ToLower(rfcSecret),
+		"GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ",
+		rfcSecret + "====",
+	} {
+		if got, err := Code(secret, time.Unix(59, 0)); err != nil || got != want {
+			t.Errorf("Code(%q) = %s, %v, want %s", secret, got, err, want)
+		}
+	}
+	if _, err := Code("not base32!", time.Unix(59, 0)); err == nil {
+		t.Error("Code(invalid secret) error = nil, want error")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	tests := []struct {
+		name     string
+		offset   time.Duration
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", 0, 1, current, true},
+		{"previous step within skew", -Period, 1, current - 1, true},
+		{"next step within skew", Period, 1, current + 1, true},
+		{"two steps behind", -2 * Period, 1, 0, false},
+		{"two steps ahead", 2 * Period, 1, 0, false},
+		{"previous step without skew", -Period, 0, 0, false},
+		{"two steps behind with wider skew", -2 * Period, 2, current - 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, now.Add(tt.offset))
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := Validate(rfcSecret, code, now, tt.skew)
+			if step != tt.wantStep || ok != tt.wantOK {
+				t.Errorf("Validate() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	code, _ := Code(rfcSecret, now)
+
+	if _, ok := Validate(rfcSecret, " "+code+" ", now, 0); !ok {
+		t.Error("Validate() rejected a code with surrounding spaces")
+	}
+	for _, bad := range []string{"", "28708", "2870820", "abcdef", "000000"} {
+		if _, ok := Validate(rfcSecret, bad, now, 1); ok {
+			t.Errorf("Validate(%q) = true, want false", bad)
+		}
+	}
+	if _, ok := Validate("not base32!", code, now, 1); ok {
+		t.Error("Validate(invalid secret) = true, want false")
+	}
+}
+
+func TestURI(t *testing.T) {
+	got := URI("Tango App", "alice@example.com", rfcSecret)
+	want := "otpauth://totp/Tango%20App:alice@example.com?algorithm=SHA1&digits=6&issuer=Tango+App&period=30&secret=" + rfcSecret
+	if got != want {
+		t.Errorf("URI() = %s, want %s", got, want)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != SecretSize {
+		t.Errorf("len(key) = %d, want %d", len(key), SecretSize)
+	}
+}